@@ -3,38 +3,119 @@ package app
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gowww/cli"
 	"github.com/gowww/compress"
 	"github.com/gowww/crypto"
-	"github.com/gowww/fatal"
 	"github.com/gowww/i18n"
 	gowwwlog "github.com/gowww/log"
 	"github.com/gowww/router"
 	"github.com/gowww/secure"
+	"github.com/gowww/view"
+	"golang.org/x/text/language"
 )
 
 var (
-	errorHandler    Handler
-	encrypter       crypto.Encrypter
-	securityOptions *secure.Options
-
-	address    string
-	production bool
-	rt         = router.New()
+	address         string
+	production      bool
+	tlsCertFile     string
+	tlsKeyFile      string
+	redirectHTTPS   string
+	shutdownTimeout time.Duration
+	printRoutes     bool
+
+	appsMu sync.Mutex
+	apps   []*App // apps are the apps made by New, all listed by flag -routes.
+
+	defaultApp *App // defaultApp is set in init as it's referenced (indirectly) by New.
 )
 
 func init() {
+	defaultApp = New()
+
 	cli.String(&address, "a", ":8080", "The address to listen and serve on.")
 	cli.Bool(&production, "p", false, "Run the server in production environment.")
+	cli.String(&tlsCertFile, "cert", "", "The TLS certificate file used to serve HTTPS. It's reloaded on SIGHUP.")
+	cli.String(&tlsKeyFile, "key", "", "The TLS private key file used to serve HTTPS. It's reloaded on SIGHUP.")
+	cli.String(&redirectHTTPS, "redirect", "", "The address of an HTTP listener redirecting to HTTPS (\":80\", for example).")
+	cli.Bool(&printRoutes, "routes", false, "Print the routes table of all apps as JSON and exit, without serving.")
+	cli.Duration(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "The maximum duration of the shutdown, for draining connections and running the shutdown hooks together.")
+}
+
+// An App contains the routes, views and settings of a web app.
+// Package-level functions use a default app so there is no need for one unless multiple apps must live in the same process.
+type App struct {
+	rt          *router.Router
+	probes      map[string]*router.Router // probes are the routers used to know which methods exist for a path.
+	routes      []*RouteEntry
+	namedRoutes map[string]*RouteEntry
+
+	notFoundHandler         Handler
+	methodNotAllowedHandler Handler
+	unauthorizedHandler     Handler
+	forbiddenHandler        Handler
+	errorHandler            Handler
+	errorView               string
+	errorMappings           []errorMapping
+
+	encrypter       *Keyring
+	securityOptions *secure.Options
+	sessionOptions  *SessionOptions
+	cookieOptions   *CookieOptions
+	corsOptions     *CORSOptions
+	corsRoutes      bool // corsRoutes tells if a route has CORS options.
+	bindLimit       int64
+	address         string
+	startHooks      []func() error
+	shutdownHooks   []func(context.Context) error
+
+	confI18n struct {
+		Locales  i18n.Locales
+		Fallback language.Tag
+		Parsers  []i18n.Parser
+	}
+
+	views     *view.View
+	viewsOnce sync.Once
+
+	encoders []Encoder // encoders are used by Context.Respond, in order of preference.
+
+	routesPrinted bool // routesPrinted tells if the routes table has been printed, with flag -routes.
+}
+
+// New returns a fresh app.
+func New() *App {
+	a := &App{
+		rt:          router.New(),
+		probes:      make(map[string]*router.Router),
+		namedRoutes: make(map[string]*RouteEntry),
+		views:       view.New(),
+		encoders:    []Encoder{EncoderJSON, EncoderXML, EncoderYAML, EncoderMsgPack, EncoderCSV},
+	}
+	a.rt.NotFoundHandler = http.HandlerFunc(a.serveUnmatched)
 
 	// Set route for static content.
-	rt.Get("/"+staticDir+"/", staticHandler)
+	a.handle(http.MethodGet, "/"+staticDir+"/", staticHandler, nil)
+
+	appsMu.Lock()
+	apps = append(apps, a)
+	appsMu.Unlock()
+
+	return a
+}
+
+// Default returns the app used by package-level functions.
+func Default() *App {
+	return defaultApp
 }
 
 // A Handler handles a request.
@@ -44,6 +125,25 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h(&Context{Res: w, Req: r})
 }
 
+// An ErrHandler handles a request and returns an error to stop with an error response.
+// An *HTTPError is responded as is and the other errors are mapped to a status with MapError ("500 Internal Server Error" by default).
+// Like when recovering, the error handler is used if set (see App.Error) and the error is available through Context.Error.
+// If the response body has already started, the error is only logged.
+type ErrHandler func(*Context) error
+
+func (h ErrHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := &Context{Res: w, Req: r}
+	err := h(c)
+	if err == nil {
+		return
+	}
+	if cw, ok := c.Get(contextKeyWriter).(*contextWriter); ok && cw.written {
+		log.Printf("Serving %s: %v (response already written)", r.RemoteAddr, err)
+		return
+	}
+	c.app().serveError(w, c.Req, c.app().httpError(err), nil)
+}
+
 // A Middleware is a handler that wraps another one.
 type Middleware func(http.Handler) http.Handler
 
@@ -56,80 +156,282 @@ func wrapHandler(h http.Handler, mm ...Middleware) http.Handler {
 }
 
 // Route makes a route for method and path.
-func Route(method, path string, handler Handler, middlewares ...Middleware) {
-	rt.Handle(method, path, wrapHandler(handler, middlewares...))
+func (a *App) Route(method, path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.handle(method, path, handler, middlewares)
+}
+
+// Route makes a route for method and path.
+func Route(method, path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Route(method, path, handler, middlewares...)
+}
+
+// RouteErr makes a route for method and path.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) RouteErr(method, path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.handle(method, path, handler, middlewares)
+}
+
+// RouteErr makes a route for method and path.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func RouteErr(method, path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.RouteErr(method, path, handler, middlewares...)
 }
 
 // Get makes a route for GET method.
-func Get(path string, handler Handler, middlewares ...Middleware) {
-	Route(http.MethodGet, path, handler, middlewares...)
+func (a *App) Get(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodGet, path, handler, middlewares...)
+}
+
+// Get makes a route for GET method.
+func Get(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Get(path, handler, middlewares...)
+}
+
+// GetErr makes a route for GET method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) GetErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodGet, path, handler, middlewares...)
+}
+
+// GetErr makes a route for GET method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func GetErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.GetErr(path, handler, middlewares...)
+}
+
+// Head makes a route for HEAD method.
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func (a *App) Head(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodHead, path, handler, middlewares...)
+}
+
+// Head makes a route for HEAD method.
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func Head(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Head(path, handler, middlewares...)
+}
+
+// HeadErr makes a route for HEAD method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func (a *App) HeadErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodHead, path, handler, middlewares...)
+}
+
+// HeadErr makes a route for HEAD method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func HeadErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.HeadErr(path, handler, middlewares...)
 }
 
 // Post makes a route for POST method.
-func Post(path string, handler Handler, middlewares ...Middleware) {
-	Route(http.MethodPost, path, handler, middlewares...)
+func (a *App) Post(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodPost, path, handler, middlewares...)
+}
+
+// Post makes a route for POST method.
+func Post(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Post(path, handler, middlewares...)
+}
+
+// PostErr makes a route for POST method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) PostErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodPost, path, handler, middlewares...)
+}
+
+// PostErr makes a route for POST method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func PostErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.PostErr(path, handler, middlewares...)
 }
 
 // Put makes a route for PUT method.
-func Put(path string, handler Handler, middlewares ...Middleware) {
-	Route(http.MethodPut, path, handler, middlewares...)
+func (a *App) Put(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodPut, path, handler, middlewares...)
+}
+
+// Put makes a route for PUT method.
+func Put(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Put(path, handler, middlewares...)
+}
+
+// PutErr makes a route for PUT method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) PutErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodPut, path, handler, middlewares...)
+}
+
+// PutErr makes a route for PUT method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func PutErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.PutErr(path, handler, middlewares...)
+}
+
+// Patch makes a route for PATCH method.
+func (a *App) Patch(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodPatch, path, handler, middlewares...)
 }
 
 // Patch makes a route for PATCH method.
-func Patch(path string, handler Handler, middlewares ...Middleware) {
-	Route(http.MethodPatch, path, handler, middlewares...)
+func Patch(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Patch(path, handler, middlewares...)
+}
+
+// PatchErr makes a route for PATCH method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) PatchErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodPatch, path, handler, middlewares...)
+}
+
+// PatchErr makes a route for PATCH method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func PatchErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.PatchErr(path, handler, middlewares...)
 }
 
 // Delete makes a route for DELETE method.
-func Delete(path string, handler Handler, middlewares ...Middleware) {
-	Route(http.MethodDelete, path, handler, middlewares...)
+func (a *App) Delete(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodDelete, path, handler, middlewares...)
+}
+
+// Delete makes a route for DELETE method.
+func Delete(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Delete(path, handler, middlewares...)
+}
+
+// DeleteErr makes a route for DELETE method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) DeleteErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodDelete, path, handler, middlewares...)
+}
+
+// DeleteErr makes a route for DELETE method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func DeleteErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.DeleteErr(path, handler, middlewares...)
+}
+
+// Options makes a route for OPTIONS method.
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func (a *App) Options(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(http.MethodOptions, path, handler, middlewares...)
+}
+
+// Options makes a route for OPTIONS method.
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func Options(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Options(path, handler, middlewares...)
+}
+
+// OptionsErr makes a route for OPTIONS method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func (a *App) OptionsErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(http.MethodOptions, path, handler, middlewares...)
+}
+
+// OptionsErr makes a route for OPTIONS method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func OptionsErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.OptionsErr(path, handler, middlewares...)
+}
+
+// Any makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+func (a *App) Any(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return a.Route(methodAny, path, handler, middlewares...)
+}
+
+// Any makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+func Any(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.Any(path, handler, middlewares...)
+}
+
+// AnyErr makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (a *App) AnyErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return a.RouteErr(methodAny, path, handler, middlewares...)
+}
+
+// AnyErr makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func AnyErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return defaultApp.AnyErr(path, handler, middlewares...)
 }
 
 // NotFound registers the "not found" handler.
-func NotFound(handler Handler) {
-	if rt.NotFoundHandler != nil {
+func (a *App) NotFound(handler Handler) {
+	if a.notFoundHandler != nil {
 		panic(`app: "not found" handler set multiple times`)
 	}
-	rt.NotFoundHandler = handler
+	a.notFoundHandler = handler
+}
+
+// NotFound registers the "not found" handler.
+func NotFound(handler Handler) {
+	defaultApp.NotFound(handler)
+}
+
+// MethodNotAllowed registers the "method not allowed" handler, used when the path exists but not for the request method.
+// The "Allow" header is already set when the handler is called.
+func (a *App) MethodNotAllowed(handler Handler) {
+	if a.methodNotAllowedHandler != nil {
+		panic(`app: "method not allowed" handler set multiple times`)
+	}
+	a.methodNotAllowedHandler = handler
+}
+
+// MethodNotAllowed registers the "method not allowed" handler, used when the path exists but not for the request method.
+// The "Allow" header is already set when the handler is called.
+func MethodNotAllowed(handler Handler) {
+	defaultApp.MethodNotAllowed(handler)
 }
 
-// Error registers the "internal error" handler.
+// Error registers the "internal error" handler, also used to respond to an HTTPError with a 5xx status.
 //
-// Using Context.Error, you can retrieve the error value stored in request's context during recovering.
-func Error(handler Handler) {
-	if errorHandler != nil {
+// Using Context.Error, you can retrieve the error recovered from the handler panic (an *HTTPError if the handler panicked with it).
+// Without this handler, errors are responded with Context.Problem.
+func (a *App) Error(handler Handler) {
+	if a.errorHandler != nil {
 		panic(`app: "internal error" handler set multiple times`)
 	}
-	errorHandler = handler
+	a.errorHandler = handler
+}
+
+// Error registers the "internal error" handler, also used to respond to an HTTPError with a 5xx status.
+//
+// Using Context.Error, you can retrieve the error recovered from the handler panic (an *HTTPError if the handler panicked with it).
+// Without this handler, errors are responded with Context.Problem.
+func Error(handler Handler) {
+	defaultApp.Error(handler)
 }
 
 // Secure sets security options.
-func Secure(o *secure.Options) {
-	if securityOptions != nil {
+func (a *App) Secure(o *secure.Options) {
+	if a.securityOptions != nil {
 		panic("app: security options set multiple times")
 	}
-	securityOptions = o
+	a.securityOptions = o
 }
 
-// Secret sets the secret key used for encryption.
-// The key must be 32 bytes long.
-func Secret(key string) {
-	if encrypter != nil {
-		panic("app: secret key set multiple times")
-	}
-	var err error
-	encrypter, err = crypto.NewEncrypter([]byte(key))
-	if err != nil {
-		panic(fmt.Errorf("app: %v", err))
+// Secure sets security options.
+func Secure(o *secure.Options) {
+	defaultApp.Secure(o)
+}
+
+// Encrypter returns the app encrypter.
+func (a *App) Encrypter() crypto.Encrypter {
+	if a.encrypter == nil {
+		panic("app: no secret key set, no encrypter")
 	}
+	return a.encrypter
 }
 
 // Encrypter returns the global encrypter.
 func Encrypter() crypto.Encrypter {
-	if encrypter == nil {
-		panic("app: no secret key set, no encrypter")
-	}
-	return encrypter
+	return defaultApp.Encrypter()
 }
 
 // EnvProduction tells if the app is run with the production flag.
@@ -141,6 +443,24 @@ func EnvProduction() bool {
 	return production
 }
 
+// SetAddress sets the address on which the app will listen and serve, instead of the one given by flag -a.
+// It's useful when multiple apps are run in the same process.
+func (a *App) SetAddress(addr string) {
+	if a.address != "" {
+		panic("app: address set multiple times")
+	}
+	a.address = addr
+}
+
+// Address gives the address on which the app is running.
+// It ensures that flags are parsed so don't use this function before setting your own flags with gowww/cli or they will be ignored.
+func (a *App) Address() string {
+	if a.address != "" {
+		return a.address
+	}
+	return Address()
+}
+
 // Address gives the address on which the app is running.
 // It ensures that flags are parsed so don't use this function before setting your own flags with gowww/cli or they will be ignored.
 func Address() string {
@@ -150,37 +470,32 @@ func Address() string {
 	return address
 }
 
-// Run ensures that flags are parsed, sets the middlewares and starts the server.
-func Run(mm ...Middleware) {
+// Handler ensures that flags are parsed, initializes the views and returns the app handler wrapped with the middlewares, exactly as Run serves it.
+// It can be used for testing or to serve the app with another server.
+func (a *App) Handler(mm ...Middleware) http.Handler {
 	if !cli.Parsed() {
 		cli.Parse()
 	}
 
-	initViews()
+	a.initViews()
 
-	handler := wrapHandler(rt, mm...)
-	handler = contextHandle(handler)
+	handler := wrapHandler(a.rt, mm...)
+	handler = corsHandle(a, handler)
+	handler = contextHandle(a, handler)
 
 	// gowww/secure
-	if securityOptions != nil {
-		securityOptions.EnvDevelopment = !production
-		handler = secure.Handle(handler, securityOptions)
+	if a.securityOptions != nil {
+		a.securityOptions.EnvDevelopment = !production
+		handler = secure.Handle(handler, a.securityOptions)
 	} else {
 		handler = secure.Handle(handler, &secure.Options{EnvDevelopment: !production})
 	}
 
-	// gowww/fatal
-	if errorHandler != nil {
-		handler = fatal.Handle(handler, &fatal.Options{RecoverHandler: errorHandler})
-	} else {
-		handler = fatal.Handle(handler, &fatal.Options{RecoverHandler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		})})
-	}
+	handler = appHandle(a, handler)
 
 	// gowww/i18n
-	if confI18n.Locales != nil {
-		handler = i18n.Handle(handler, confI18n.Locales, confI18n.Fallback, confI18n.Parsers...)
+	if a.confI18n.Locales != nil {
+		handler = i18n.Handle(handler, a.confI18n.Locales, a.confI18n.Fallback, a.confI18n.Parsers...)
 	}
 
 	// gowww/compress
@@ -191,21 +506,150 @@ func Run(mm ...Middleware) {
 		handler = gowwwlog.Handle(handler, &gowwwlog.Options{Color: true})
 	}
 
+	return handler
+}
+
+// OnStart registers a function called before the server starts listening.
+// Functions are called in the order they are registered and the app exits if one of them fails.
+func (a *App) OnStart(f func() error) {
+	a.startHooks = append(a.startHooks, f)
+}
+
+// OnStart registers a function called before the server starts listening.
+// Functions are called in the order they are registered and the app exits if one of them fails.
+func OnStart(f func() error) {
+	defaultApp.OnStart(f)
+}
+
+// OnShutdown registers a function called when the server is shut down, after the open connections are drained.
+// Functions are called in the reverse order they are registered (like deferred calls) so resources opened first are closed last.
+// The context passed to f expires at the end of the shutdown timeout (flag -shutdown-timeout), which also bounds the draining.
+func (a *App) OnShutdown(f func(context.Context) error) {
+	a.shutdownHooks = append(a.shutdownHooks, f)
+}
+
+// OnShutdown registers a function called when the server is shut down, after the open connections are drained.
+// Functions are called in the reverse order they are registered (like deferred calls) so resources opened first are closed last.
+// The context passed to f expires at the end of the shutdown timeout (flag -shutdown-timeout), which also bounds the draining.
+func OnShutdown(f func(context.Context) error) {
+	defaultApp.OnShutdown(f)
+}
+
+// Run ensures that flags are parsed, sets the middlewares and starts the server.
+// The server is gracefully shut down on SIGINT or SIGTERM.
+func (a *App) Run(mm ...Middleware) {
+	handler := a.Handler(mm...)
+
+	if printRoutes {
+		printAppsRoutes(a)
+		return
+	}
+
+	srv := &http.Server{Addr: a.Address(), Handler: handler}
+	servers := []*http.Server{srv}
+
+	// TLS
+	useTLS := tlsCertFile != "" || tlsKeyFile != ""
+	if useTLS {
+		cr, err := newCertReloader(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			log.Fatalf("Could not load TLS certificate: %v", err)
+		}
+		srv.TLSConfig = &tls.Config{GetCertificate: cr.GetCertificate}
+		if redirectHTTPS != "" {
+			servers = append(servers, &http.Server{Addr: redirectHTTPS, Handler: httpsRedirectHandler(srv.Addr)})
+		}
+	} else if redirectHTTPS != "" {
+		log.Fatal("Could not redirect to HTTPS: no TLS certificate set")
+	}
+
+	for _, f := range a.startHooks {
+		if err := f(); err != nil {
+			log.Fatalf("Could not start: %v", err)
+		}
+	}
+
 	// Wait for shut down.
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
-	srv := &http.Server{Addr: address, Handler: handler}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
 	go func() {
 		<-quit
 		log.Println("Shutting down...")
-		if err := srv.Shutdown(context.Background()); err != nil {
-			log.Fatalf("Could not shut down: %v", err)
-		}
+		a.shutdown(servers)
+		close(done)
 	}()
 
-	log.Printf("Running on %v", address)
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	for _, rsrv := range servers[1:] {
+		go func(rsrv *http.Server) {
+			log.Printf("Redirecting to HTTPS from %v", rsrv.Addr)
+			if err := rsrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}(rsrv)
+	}
+
+	var err error
+	if useTLS {
+		log.Printf("Running on %v with TLS", srv.Addr)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("Running on %v", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	<-done
 	log.Println("Gracefully shut down")
 }
+
+// appRoutes is the routes table of an app, as printed with flag -routes.
+type appRoutes struct {
+	Address string      `json:"address"`
+	Routes  []RouteInfo `json:"routes"`
+}
+
+// printAppsRoutes prints the routes table of each app made by New and not printed yet, as JSON lines with the app address to tell apps apart.
+// All apps are printed by the first one run, so none is left out when the program exits as soon as its main app returns (the others being run in goroutines).
+// Apps having only the static route (never used) are skipped, unless it's run app.
+func printAppsRoutes(run *App) {
+	appsMu.Lock()
+	defer appsMu.Unlock()
+	for _, a := range apps {
+		if a.routesPrinted || a != run && len(a.routes) == 1 {
+			continue
+		}
+		a.routesPrinted = true
+		b, err := json.Marshal(appRoutes{Address: a.Address(), Routes: a.Routes()})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err = os.Stdout.Write(append(b, '\n')); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// shutdown drains the servers connections and calls the shutdown hooks, all within the shutdown timeout.
+// Connections still open after the timeout are closed.
+func (a *App) shutdown(servers []*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Could not drain connections on %v: %v", srv.Addr, err)
+			srv.Close()
+		}
+	}
+	for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+		if err := a.shutdownHooks[i](ctx); err != nil {
+			log.Printf("Shutdown error: %v", err)
+		}
+	}
+}
+
+// Run ensures that flags are parsed, sets the middlewares and starts the server.
+func Run(mm ...Middleware) {
+	defaultApp.Run(mm...)
+}
@@ -0,0 +1,182 @@
+/*
+Package apptest provides utilities for end-to-end testing of a gowww/app.
+
+A Client fires requests against the whole app handler (exactly as app.Run serves it), keeps cookies between requests and follows redirects:
+
+	c := apptest.New(t, a)
+	c.PostForm("/login", url.Values{"email": {"me@example.com"}, "password": {"secret"}}).
+		Status(http.StatusOK).
+		View("dashboard")
+	c.Get("/account").Status(http.StatusOK)
+*/
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+)
+
+// maxRedirects is the number of redirects followed before giving up.
+const maxRedirects = 10
+
+// A Client fires requests against an app and keeps its cookies.
+type Client struct {
+	// FollowRedirects tells if the client follows the redirect responses. Defaults to true.
+	FollowRedirects bool
+
+	t       testing.TB
+	app     *app.App
+	handler http.Handler
+	baseURL *url.URL
+	jar     http.CookieJar
+	header  http.Header
+}
+
+// New returns a client for app a, with its handler wrapped with middlewares mm.
+func New(t testing.TB, a *app.App, mm ...app.Middleware) *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		FollowRedirects: true,
+		t:               t,
+		app:             a,
+		handler:         a.Handler(mm...),
+		baseURL:         &url.URL{Scheme: "http", Host: "example.com"},
+		jar:             jar,
+		header:          make(http.Header),
+	}
+}
+
+// SetHeader sets a header sent with all subsequent requests.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// SetCookie stores a cookie sent with all subsequent requests.
+// The value is sent as is, without encryption.
+func (c *Client) SetCookie(cookie *http.Cookie) *Client {
+	c.jar.SetCookies(c.baseURL, []*http.Cookie{cookie})
+	return c
+}
+
+// Cookies returns the cookies stored by the client.
+func (c *Client) Cookies() []*http.Cookie {
+	return c.jar.Cookies(c.baseURL)
+}
+
+// Get fires a GET request.
+func (c *Client) Get(path string) *Response {
+	return c.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// Head fires a HEAD request.
+func (c *Client) Head(path string) *Response {
+	return c.Do(httptest.NewRequest(http.MethodHead, path, nil))
+}
+
+// Delete fires a DELETE request.
+func (c *Client) Delete(path string) *Response {
+	return c.Do(httptest.NewRequest(http.MethodDelete, path, nil))
+}
+
+// PostForm fires a POST request with an URL encoded form.
+func (c *Client) PostForm(path string, form url.Values) *Response {
+	return c.Form(http.MethodPost, path, form)
+}
+
+// Form fires a request with method and an URL encoded form.
+func (c *Client) Form(method, path string, form url.Values) *Response {
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(r)
+}
+
+// PostJSON fires a POST request with v marshalled as the JSON body.
+func (c *Client) PostJSON(path string, v interface{}) *Response {
+	return c.JSON(http.MethodPost, path, v)
+}
+
+// JSON fires a request with method and v marshalled as the JSON body.
+func (c *Client) JSON(method, path string, v interface{}) *Response {
+	c.t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	return c.Do(r)
+}
+
+// Do fires a request with the client headers and cookies.
+// If FollowRedirects is true, redirections are followed and the last response is returned.
+func (c *Client) Do(r *http.Request) *Response {
+	c.t.Helper()
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	for i := 0; ; i++ {
+		res := c.do(r, body)
+		loc := res.Recorder.Header().Get("Location")
+		if !c.FollowRedirects || loc == "" || res.Recorder.Code < 300 || res.Recorder.Code >= 400 {
+			return res
+		}
+		if i == maxRedirects {
+			c.t.Fatalf("apptest: stopped after %d redirects", maxRedirects)
+		}
+		u, err := r.URL.Parse(loc)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		method := r.Method
+		if res.Recorder.Code != http.StatusTemporaryRedirect && res.Recorder.Code != http.StatusPermanentRedirect {
+			method, body = http.MethodGet, nil
+		}
+		next := httptest.NewRequest(method, u.RequestURI(), nil)
+		if body != nil {
+			next.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+		}
+		r = next
+	}
+}
+
+// do fires a single request and records the response.
+func (c *Client) do(r *http.Request, body []byte) *Response {
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	for k, vv := range c.header {
+		r.Header[k] = vv
+	}
+	for _, ck := range c.jar.Cookies(c.baseURL) {
+		r.AddCookie(ck)
+	}
+
+	res := &Response{t: c.t, client: c, Req: r}
+	r = app.RequestWithViewHook(r, func(name string, data app.ViewData) {
+		res.ViewName = name
+		res.ViewData = data
+	})
+	res.Recorder = httptest.NewRecorder()
+	c.handler.ServeHTTP(res.Recorder, r)
+
+	c.jar.SetCookies(c.baseURL, res.Recorder.Result().Cookies())
+	return res
+}
@@ -0,0 +1,113 @@
+package apptest_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+	"github.com/gowww/check"
+)
+
+func TestMain(m *testing.M) {
+	if err := os.Chdir("testdata"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+var joinChecker = check.Checker{
+	"email": {check.Required, check.Email},
+}
+
+func newApp() *app.App {
+	a := app.New()
+	a.Secret("3rpJFaa8Jtx2TFzWV2n0ymFbcuBBP7y6")
+	a.Get("/json", func(c *app.Context) {
+		c.Status(http.StatusCreated)
+		c.JSON(map[string]interface{}{"id": 1})
+	})
+	a.Get("/join", func(c *app.Context) {
+		c.View("join")
+	})
+	a.Post("/join", func(c *app.Context) {
+		if c.BadRequest(joinChecker, "join") {
+			return
+		}
+		c.SetCookie(&http.Cookie{Name: "user", Value: c.FormValue("email"), Path: "/"})
+		c.Redirect("/account", http.StatusSeeOther)
+	})
+	a.Post("/signup", func(c *app.Context) {
+		if errs := c.Check(joinChecker); !errs.Empty() {
+			c.FlashErrors(errs)
+			c.Redirect("/join", http.StatusSeeOther)
+			return
+		}
+		c.Flash("success", "Welcome!")
+		c.Redirect("/join", http.StatusSeeOther)
+	})
+	a.Get("/account", func(c *app.Context) {
+		c.Text("Hello " + c.Cookie("user"))
+	})
+	return a
+}
+
+func TestClientJSON(t *testing.T) {
+	apptest.New(t, newApp()).Get("/json").
+		Status(http.StatusCreated).
+		Header("Content-Type", "application/json").
+		JSON(map[string]int{"id": 1})
+}
+
+func TestClientView(t *testing.T) {
+	c := apptest.New(t, newApp())
+	c.Get("/join").
+		Status(http.StatusOK).
+		View("join").
+		NoCheckErrors()
+	c.PostForm("/join", url.Values{"email": {"wrong"}}).
+		Status(http.StatusBadRequest).
+		View("join").
+		CheckError("email", "It's not an email.").
+		BodyContains("It&#39;s not an email.")
+}
+
+func TestClientRedirectAndCookies(t *testing.T) {
+	c := apptest.New(t, newApp())
+	c.FollowRedirects = false
+	res := c.PostForm("/join", url.Values{"email": {"me@example.com"}}).
+		Status(http.StatusSeeOther).
+		Header("Location", "/account").
+		CookieValue("user", "me@example.com")
+	if res.Recorder.Result().Cookies()[0].Value == "me@example.com" {
+		t.Error("cookie: want encrypted value")
+	}
+
+	c.FollowRedirects = true
+	c.Get("/account").Body("Hello me@example.com")
+}
+
+func TestClientFollowRedirects(t *testing.T) {
+	apptest.New(t, newApp()).PostForm("/join", url.Values{"email": {"me@example.com"}}).
+		Status(http.StatusOK).
+		Body("Hello me@example.com")
+}
+
+func TestClientFlash(t *testing.T) {
+	c := apptest.New(t, newApp())
+	c.FollowRedirects = false
+	c.PostForm("/signup", url.Values{"email": {"wrong"}}).
+		Status(http.StatusSeeOther).
+		FlashCheckError("email", "It's not an email.")
+	c.Get("/join").
+		CheckError("email", "It's not an email.").
+		ViewDataValue("form", url.Values{"email": {"wrong"}}).
+		BodyContains(`value="wrong"`)
+	c.Get("/join").NoCheckErrors()
+
+	c.PostForm("/signup", url.Values{"email": {"me@example.com"}}).Flash("success", "Welcome!")
+	c.Get("/join").BodyContains(`<p class="success">Welcome!</p>`)
+	c.Get("/join").ViewDataValue("flashes", []app.Flash(nil))
+}
@@ -0,0 +1,197 @@
+package apptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/check"
+)
+
+// A Response is a recorded app response, with assertion helpers.
+// All assertions report failures to the test and return the response so they can be chained.
+type Response struct {
+	Recorder *httptest.ResponseRecorder // Recorder is the recorded response, like Recorder.Body or Recorder.Result().
+	Req      *http.Request              // Req is the request fired for this response.
+	ViewName string                     // ViewName is the name of the last rendered view, if any.
+	ViewData app.ViewData               // ViewData is the data of the last rendered view, if any.
+
+	t      testing.TB
+	client *Client
+}
+
+// Status asserts that the response has status code.
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.t.Errorf("%s %s: status: want %d, got %d", r.Req.Method, r.Req.URL, code, r.Recorder.Code)
+	}
+	return r
+}
+
+// Header asserts that the response has header key with value.
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if v := r.Recorder.Result().Header.Get(key); v != value {
+		r.t.Errorf("%s %s: header %q: want %q, got %q", r.Req.Method, r.Req.URL, key, value, v)
+	}
+	return r
+}
+
+// Body asserts that the response body is s.
+func (r *Response) Body(s string) *Response {
+	r.t.Helper()
+	if b := r.Recorder.Body.String(); b != s {
+		r.t.Errorf("%s %s: body: want %q, got %q", r.Req.Method, r.Req.URL, s, b)
+	}
+	return r
+}
+
+// BodyContains asserts that the response body contains s.
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if b := r.Recorder.Body.String(); !strings.Contains(b, s) {
+		r.t.Errorf("%s %s: body: want to contain %q, got %q", r.Req.Method, r.Req.URL, s, b)
+	}
+	return r
+}
+
+// DecodeJSON unmarshals the JSON response body into v.
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), v); err != nil {
+		r.t.Errorf("%s %s: decoding JSON body: %v", r.Req.Method, r.Req.URL, err)
+	}
+	return r
+}
+
+// JSON asserts that the response body is the JSON representation of v.
+// Both are compared after being decoded so formatting and keys order don't matter.
+func (r *Response) JSON(v interface{}) *Response {
+	r.t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	var want, got interface{}
+	if err = json.Unmarshal(b, &want); err != nil {
+		r.t.Fatal(err)
+	}
+	if err = json.Unmarshal(r.Recorder.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("%s %s: decoding JSON body: %v", r.Req.Method, r.Req.URL, err)
+		return r
+	}
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("%s %s: JSON body: want %s, got %s", r.Req.Method, r.Req.URL, b, strings.TrimSpace(r.Recorder.Body.String()))
+	}
+	return r
+}
+
+// View asserts that the view name has been rendered.
+func (r *Response) View(name string) *Response {
+	r.t.Helper()
+	if r.ViewName != name {
+		r.t.Errorf("%s %s: view: want %q, got %q", r.Req.Method, r.Req.URL, name, r.ViewName)
+	}
+	return r
+}
+
+// ViewDataValue asserts that the rendered view data contains key with value v.
+func (r *Response) ViewDataValue(key string, v interface{}) *Response {
+	r.t.Helper()
+	got, ok := r.ViewData[key]
+	if !ok {
+		r.t.Errorf("%s %s: view data %q: not found", r.Req.Method, r.Req.URL, key)
+	} else if !reflect.DeepEqual(got, v) {
+		r.t.Errorf("%s %s: view data %q: want %#v, got %#v", r.Req.Method, r.Req.URL, key, v, got)
+	}
+	return r
+}
+
+// Cookie returns the value of the named cookie set by the response, decrypted the same way Context.Cookie does.
+// If the response doesn't set the cookie, an empty string is returned.
+func (r *Response) Cookie(name string) string {
+	return r.nextContext().Cookie(name)
+}
+
+// nextContext returns a context for a request sending the cookies set by the response.
+func (r *Response) nextContext() *app.Context {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, ck := range r.Recorder.Result().Cookies() {
+		if ck.MaxAge >= 0 {
+			req.AddCookie(ck)
+		}
+	}
+	return r.client.app.NewContext(httptest.NewRecorder(), req)
+}
+
+// CookieValue asserts that the response sets the named cookie with value (after decryption).
+func (r *Response) CookieValue(name, value string) *Response {
+	r.t.Helper()
+	if v := r.Cookie(name); v != value {
+		r.t.Errorf("%s %s: cookie %q: want %q, got %q", r.Req.Method, r.Req.URL, name, value, v)
+	}
+	return r
+}
+
+// Flashes returns the messages flashed by the response (see Context.Flash).
+func (r *Response) Flashes() []app.Flash {
+	return r.nextContext().Flashes()
+}
+
+// Flash asserts that the response flashes msg with kind.
+func (r *Response) Flash(kind, msg string) *Response {
+	r.t.Helper()
+	flashes := r.Flashes()
+	for _, f := range flashes {
+		if f.Kind == kind && f.Message == msg {
+			return r
+		}
+	}
+	r.t.Errorf("%s %s: flash %q: want %q, got %v", r.Req.Method, r.Req.URL, kind, msg, flashes)
+	return r
+}
+
+// FlashCheckError asserts that the first checking error flashed for key is msg (see Context.FlashErrors).
+func (r *Response) FlashCheckError(key, msg string) *Response {
+	r.t.Helper()
+	if v := r.nextContext().FlashedErrors().First(key); v != msg {
+		r.t.Errorf("%s %s: flashed checking error %q: want %q, got %q", r.Req.Method, r.Req.URL, key, msg, v)
+	}
+	return r
+}
+
+// errors returns the checking errors from the rendered view data or from the JSON body.
+func (r *Response) errors() check.TranslatedErrors {
+	if errs, ok := r.ViewData["errors"].(check.TranslatedErrors); ok {
+		return errs
+	}
+	var body struct {
+		Errors check.TranslatedErrors `json:"errors"`
+	}
+	json.Unmarshal(r.Recorder.Body.Bytes(), &body)
+	return body.Errors
+}
+
+// CheckError asserts that the first checking error for key is msg.
+// Errors are taken from the rendered view data (translated) or from the JSON body (as produced by Context.BadRequest).
+func (r *Response) CheckError(key, msg string) *Response {
+	r.t.Helper()
+	if v := r.errors().First(key); v != msg {
+		r.t.Errorf("%s %s: checking error %q: want %q, got %q", r.Req.Method, r.Req.URL, key, msg, v)
+	}
+	return r
+}
+
+// NoCheckErrors asserts that there is no checking error.
+func (r *Response) NoCheckErrors() *Response {
+	r.t.Helper()
+	if errs := r.errors(); len(errs) > 0 {
+		r.t.Errorf("%s %s: checking errors: want none, got %v", r.Req.Method, r.Req.URL, errs)
+	}
+	return r
+}
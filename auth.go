@@ -0,0 +1,340 @@
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A Principal is an authenticated user, set by an authentication middleware.
+type Principal struct {
+	ID     string                 // ID identifies the user: the username, the token owner or the JWT subject.
+	Roles  []string               // Roles are the user roles, used by authorization policies.
+	Claims map[string]interface{} // Claims are the JWT claims, if any.
+}
+
+// HasRole tells if the principal has role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && containsString(p.Roles, role)
+}
+
+// User returns the authenticated user, or nil.
+// In views, it's available as .c.User.
+func (c *Context) User() *Principal {
+	p, _ := c.Get(contextKeyUser).(*Principal)
+	return p
+}
+
+// authHandler returns a middleware authenticating requests with authenticate, which returns the principal or nil.
+// The middleware is named after function constructor in the routes listing.
+// An unauthenticated request is responded by the "unauthorized" handler (see App.Unauthorized), with the challenge for the request in the "WWW-Authenticate" header.
+func authHandler(constructor interface{}, challenge func(*http.Request) string, authenticate func(*Context) *Principal) Middleware {
+	name := funcName(constructor)
+	return func(h http.Handler) http.Handler {
+		auth := Handler(func(c *Context) {
+			p := authenticate(c)
+			if p == nil {
+				c.Res.Header().Set("WWW-Authenticate", challenge(c.Req))
+				c.app().serveUnauthorized(c)
+				return
+			}
+			h.ServeHTTP(c.Res, c.Req.WithContext(context.WithValue(c.Req.Context(), contextKeyUser, p)))
+		})
+		return namedHandler{auth, name}
+	}
+}
+
+// BasicAuth returns a middleware authenticating requests with HTTP Basic authentication, against accounts (usernames with their passwords).
+// Passwords are compared in constant time.
+func BasicAuth(realm string, accounts map[string]string) Middleware {
+	hashed := make(map[string][]byte, len(accounts))
+	for user, pass := range accounts {
+		hashed[user] = sha256Sum(pass)
+	}
+	return basicAuth(BasicAuth, realm, func(_ *Context, user, pass string) *Principal {
+		want, ok := hashed[user]
+		if !ok {
+			want = sha256Sum("") // Same work when the user doesn't exist.
+		}
+		if subtle.ConstantTimeCompare(sha256Sum(pass), want) != 1 || !ok {
+			return nil
+		}
+		return &Principal{ID: user}
+	})
+}
+
+// BasicAuthFunc returns a middleware authenticating requests with HTTP Basic authentication.
+// Function validate returns the principal for the credentials, or nil if they are invalid.
+func BasicAuthFunc(realm string, validate func(c *Context, user, pass string) *Principal) Middleware {
+	return basicAuth(BasicAuthFunc, realm, validate)
+}
+
+// basicAuth returns a middleware authenticating requests with HTTP Basic authentication, named after function constructor.
+func basicAuth(constructor interface{}, realm string, validate func(c *Context, user, pass string) *Principal) Middleware {
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+	return authHandler(constructor, func(*http.Request) string { return challenge }, func(c *Context) *Principal {
+		user, pass, ok := c.Req.BasicAuth()
+		if !ok {
+			return nil
+		}
+		return validate(c, user, pass)
+	})
+}
+
+// BearerToken returns a middleware authenticating requests with a static bearer token, among tokens with their principal.
+// Tokens are compared in constant time.
+func BearerToken(tokens map[string]*Principal) Middleware {
+	type entry struct {
+		hash []byte
+		p    *Principal
+	}
+	entries := make([]entry, 0, len(tokens))
+	for token, p := range tokens {
+		entries = append(entries, entry{sha256Sum(token), p})
+	}
+	return bearerTokenAuth(BearerToken, func(_ *Context, token string) *Principal {
+		hash := sha256Sum(token)
+		var p *Principal
+		for _, e := range entries { // Compare all tokens to not leak which one matches.
+			if subtle.ConstantTimeCompare(hash, e.hash) == 1 {
+				p = e.p
+			}
+		}
+		return p
+	})
+}
+
+// BearerTokenFunc returns a middleware authenticating requests with a bearer token (from the "Authorization" header).
+// Function validate returns the principal for the token, or nil if it's invalid.
+func BearerTokenFunc(validate func(c *Context, token string) *Principal) Middleware {
+	return bearerTokenAuth(BearerTokenFunc, validate)
+}
+
+// bearerTokenAuth returns a middleware authenticating requests with a bearer token, named after function constructor.
+func bearerTokenAuth(constructor interface{}, validate func(c *Context, token string) *Principal) Middleware {
+	return authHandler(constructor, func(*http.Request) string { return "Bearer" }, func(c *Context) *Principal {
+		token := bearerToken(c.Req)
+		if token == "" {
+			return nil
+		}
+		return validate(c, token)
+	})
+}
+
+// bearerToken returns the bearer token of the request, or an empty string.
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
+
+// sha256Sum returns the SHA-256 hash of s.
+func sha256Sum(s string) []byte {
+	h := sha256.Sum256([]byte(s))
+	return h[:]
+}
+
+// JWTOptions are the options of the JWT authentication.
+type JWTOptions struct {
+	// Key verifies the token signature: a []byte secret for HS256 or an ed25519.PublicKey for EdDSA.
+	// Tokens signed with another algorithm are rejected.
+	Key interface{}
+
+	Realm    string        // Realm is the protection space sent in the "WWW-Authenticate" challenge, if set.
+	Issuer   string        // Issuer, if set, must be the "iss" claim.
+	Audience string        // Audience, if set, must be (or be part of) the "aud" claim.
+	Leeway   time.Duration // Leeway is the tolerated clock skew for the "exp" and "nbf" claims.
+
+	// Principal returns the principal for valid claims, or nil to reject the token.
+	// By default, the ID is the "sub" claim and the roles are the "roles" claim.
+	Principal func(claims map[string]interface{}) *Principal
+}
+
+// JWT returns a middleware authenticating requests with a JSON Web Token sent as a bearer token.
+// The token must be signed with HS256 or EdDSA (depending on the key), and have a valid "exp" claim.
+//
+// As in RFC 6750, a request without token is challenged with the realm only, and one with a rejected token gets the "invalid_token" error.
+func JWT(o *JWTOptions) Middleware {
+	if o == nil {
+		panic("app: JWT options not set")
+	}
+	if _, err := jwtAlgorithm(o.Key); err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+	return authHandler(JWT, o.challenge, func(c *Context) *Principal {
+		token := bearerToken(c.Req)
+		if token == "" {
+			return nil
+		}
+		claims, err := o.verify(token, time.Now())
+		if err != nil {
+			return nil
+		}
+		if o.Principal != nil {
+			return o.Principal(claims)
+		}
+		return claimsPrincipal(claims)
+	})
+}
+
+// challenge returns the "WWW-Authenticate" challenge for a request not authenticated by the JWT middleware.
+func (o *JWTOptions) challenge(r *http.Request) string {
+	var params []string
+	if o.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", o.Realm))
+	}
+	if bearerToken(r) != "" {
+		params = append(params, `error="invalid_token"`)
+	}
+	if len(params) == 0 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// claimsPrincipal returns the default principal for JWT claims.
+func claimsPrincipal(claims map[string]interface{}) *Principal {
+	p := &Principal{Claims: claims}
+	p.ID, _ = claims["sub"].(string)
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range roles {
+			if r, ok := r.(string); ok {
+				p.Roles = append(p.Roles, r)
+			}
+		}
+	}
+	return p
+}
+
+// jwtAlgorithm returns the JWT algorithm for a key.
+func jwtAlgorithm(key interface{}) (string, error) {
+	switch key.(type) {
+	case []byte:
+		return "HS256", nil
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return "EdDSA", nil
+	}
+	return "", fmt.Errorf("JWT key must be a []byte or an ed25519 key, not %T", key)
+}
+
+// verify checks the token signature and claims, and returns the claims.
+func (o *JWTOptions) verify(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	alg, _ := jwtAlgorithm(o.Key)
+	if header.Alg != alg {
+		return nil, fmt.Errorf("unexpected algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch key := o.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, signed, sig) {
+			return nil, errors.New("invalid signature")
+		}
+	case ed25519.PrivateKey:
+		if !ed25519.Verify(key.Public().(ed25519.PublicKey), signed, sig) {
+			return nil, errors.New("invalid signature")
+		}
+	}
+
+	var claims map[string]interface{}
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(o.Leeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(o.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	if o.Issuer != "" && claims["iss"] != o.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if o.Audience != "" && !jwtAudience(claims["aud"], o.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+	return claims, nil
+}
+
+// jwtAudience tells if the "aud" claim contains audience.
+func jwtAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeJWTPart decodes a base64url encoded JSON part of a token into v.
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// NewJWT returns a JSON Web Token with claims, signed with key: a []byte secret for HS256 or an ed25519.PrivateKey for EdDSA.
+func NewJWT(claims map[string]interface{}, key interface{}) (string, error) {
+	alg, err := jwtAlgorithm(key)
+	if err != nil {
+		return "", fmt.Errorf("app: %v", err)
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, []byte(signed))
+	default:
+		return "", errors.New("app: a JWT can't be signed with an ed25519 public key")
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
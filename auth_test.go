@@ -0,0 +1,164 @@
+package app_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func newAuthApp() *app.App {
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		c.Text(c.User().ID)
+	})
+	return a
+}
+
+func authRequest(auth string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return req
+}
+
+func TestBasicAuth(t *testing.T) {
+	c := apptest.New(t, newAuthApp(), app.BasicAuth("admin", map[string]string{"alice": "s3cret"}))
+	c.Do(authRequest("")).
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+
+	req := authRequest("")
+	req.SetBasicAuth("alice", "s3cret")
+	c.Do(req).Status(http.StatusOK).Body("alice")
+
+	req = authRequest("")
+	req.SetBasicAuth("alice", "wrong")
+	c.Do(req).Status(http.StatusUnauthorized)
+
+	req = authRequest("")
+	req.SetBasicAuth("bob", "")
+	c.Do(req).Status(http.StatusUnauthorized)
+}
+
+func TestBearerToken(t *testing.T) {
+	c := apptest.New(t, newAuthApp(), app.BearerToken(map[string]*app.Principal{"t0k3n": {ID: "ci"}}))
+	c.Do(authRequest("")).Status(http.StatusUnauthorized).Header("WWW-Authenticate", "Bearer")
+	c.Do(authRequest("Bearer t0k3n")).Status(http.StatusOK).Body("ci")
+	c.Do(authRequest("Bearer other")).Status(http.StatusUnauthorized)
+
+	c = apptest.New(t, newAuthApp(), app.BearerTokenFunc(func(_ *app.Context, token string) *app.Principal {
+		if token == "valid" {
+			return &app.Principal{ID: "func"}
+		}
+		return nil
+	}))
+	c.Do(authRequest("bearer valid")).Status(http.StatusOK).Body("func")
+	c.Do(authRequest("Bearer invalid")).Status(http.StatusUnauthorized)
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("ab7d8ee1c25e4f6a8d8b4a9c27f1f1e3")
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "alice", "iss": "gowww", "aud": []string{"api"}, "exp": now + 60, "roles": []string{"admin"}}
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		key    interface{}
+		status int
+	}{
+		{"valid", valid, secret, http.StatusOK},
+		{"wrong key", valid, []byte("other"), http.StatusUnauthorized},
+		{"wrong algorithm", valid, priv, http.StatusUnauthorized},
+		{"expired", map[string]interface{}{"sub": "alice", "iss": "gowww", "aud": "api", "exp": now - 60}, secret, http.StatusUnauthorized},
+		{"no expiry", map[string]interface{}{"sub": "alice", "iss": "gowww", "aud": "api"}, secret, http.StatusUnauthorized},
+		{"not before", map[string]interface{}{"sub": "alice", "iss": "gowww", "aud": "api", "exp": now + 120, "nbf": now + 60}, secret, http.StatusUnauthorized},
+		{"wrong issuer", map[string]interface{}{"sub": "alice", "iss": "other", "aud": "api", "exp": now + 60}, secret, http.StatusUnauthorized},
+		{"wrong audience", map[string]interface{}{"sub": "alice", "iss": "gowww", "aud": "web", "exp": now + 60}, secret, http.StatusUnauthorized},
+	}
+	c := apptest.New(t, newAuthApp(), app.JWT(&app.JWTOptions{Key: secret, Issuer: "gowww", Audience: "api"}))
+	for _, tc := range cases {
+		token, err := app.NewJWT(tc.claims, tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Do(authRequest("Bearer " + token)).Recorder.Code; got != tc.status {
+			t.Errorf("JWT %s: want status %d, got %d", tc.name, tc.status, got)
+		}
+	}
+	c.Do(authRequest("")).Status(http.StatusUnauthorized).Header("WWW-Authenticate", "Bearer")
+
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		if !c.User().HasRole("admin") {
+			t.Errorf("JWT principal: want role %q, got %v", "admin", c.User().Roles)
+		}
+		c.Text(c.User().ID)
+	})
+	c = apptest.New(t, a, app.JWT(&app.JWTOptions{Key: pub, Realm: "api"}))
+	token, err := app.NewJWT(valid, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Do(authRequest("Bearer " + token)).Status(http.StatusOK).Body("alice")
+	c.Do(authRequest("Bearer x.y.z")).
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+	c.Do(authRequest("")).
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate", `Bearer realm="api"`)
+}
+
+func TestJWTNoOptions(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("JWT without options: want panic")
+		}
+	}()
+	app.JWT(nil)
+}
+
+func TestAuthSkipScheme(t *testing.T) {
+	a := app.New()
+	ok := func(c *app.Context) { c.Text(c.User().ID) }
+	both := a.Group("/api")
+	basic := both.Use(app.BasicAuth("admin", map[string]string{"alice": "s3cret"}))
+	bearer := both.Use(app.BearerToken(map[string]*app.Principal{"t0k3n": {ID: "ci"}}))
+	{
+		both.Group("/basic").Skip(bearer).Get("/me", ok)
+		both.Group("/bearer").Skip(basic).Get("/me", ok)
+	}
+
+	c := apptest.New(t, a)
+	req := authRequest("Bearer t0k3n")
+	req.URL.Path = "/api/basic/me"
+	c.Do(req).Status(http.StatusUnauthorized).Header("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+	req = authRequest("")
+	req.URL.Path = "/api/basic/me"
+	req.SetBasicAuth("alice", "s3cret")
+	c.Do(req).Status(http.StatusOK).Body("alice")
+
+	c.Do(authRequestPath("/api/bearer/me", "t0k3n")).Status(http.StatusOK).Body("ci")
+	req = authRequest("")
+	req.URL.Path = "/api/bearer/me"
+	req.SetBasicAuth("alice", "s3cret")
+	c.Do(req).Status(http.StatusUnauthorized).Header("WWW-Authenticate", "Bearer")
+
+	names := map[string]string{"/api/basic/me": "github.com/gowww/app.BasicAuth", "/api/bearer/me": "github.com/gowww/app.BearerToken"}
+	for _, r := range a.Routes() {
+		if want, ok := names[r.Path]; ok && (len(r.Middlewares) != 1 || r.Middlewares[0] != want) {
+			t.Errorf("route %s middlewares: want [%s], got %v", r.Path, want, r.Middlewares)
+		}
+	}
+}
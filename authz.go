@@ -0,0 +1,148 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A Policy authorizes a request, for the authenticated user (see Context.User), or nil if anonymous.
+type Policy interface {
+	Allow(p *Principal, r *http.Request) bool
+	String() string // String describes the policy, for the routes table.
+}
+
+// rolePolicy requires all its roles.
+type rolePolicy []string
+
+// Require returns a policy requiring an authenticated user with all the roles.
+func Require(roles ...string) Policy {
+	return rolePolicy(roles)
+}
+
+func (roles rolePolicy) Allow(p *Principal, _ *http.Request) bool {
+	if p == nil {
+		return false
+	}
+	for _, role := range roles {
+		if !p.HasRole(role) {
+			return false
+		}
+	}
+	return true
+}
+
+func (roles rolePolicy) String() string {
+	return "require(" + strings.Join(roles, ", ") + ")"
+}
+
+// PolicyFunc is a function used as a policy.
+// The principal is nil for an anonymous request.
+type PolicyFunc func(p *Principal, r *http.Request) bool
+
+// Allow implements Policy.
+func (f PolicyFunc) Allow(p *Principal, r *http.Request) bool {
+	return f(p, r)
+}
+
+// String implements Policy with the function name.
+func (f PolicyFunc) String() string {
+	return funcName(f)
+}
+
+// Authorize adds policies to the route.
+// They are all checked just before the route handler, after its middlewares (so after authentication).
+// A denied request is responded by the "unauthorized" handler if it's anonymous, or by the "forbidden" handler otherwise.
+func (re *RouteEntry) Authorize(policies ...Policy) *RouteEntry {
+	re.policies = append(re.policies, policies...)
+	return re
+}
+
+// Authorize adds policies to the group (and its subgroups), including the routes already made.
+func (rg *RouterGroup) Authorize(policies ...Policy) *RouterGroup {
+	rg.policies = append(rg.policies, policies...)
+	return rg
+}
+
+// policiesChain returns the inherited and own policies of the group.
+func (rg *RouterGroup) policiesChain() []Policy {
+	if rg.parent == nil {
+		return rg.policies
+	}
+	return append(append([]Policy(nil), rg.parent.policiesChain()...), rg.policies...)
+}
+
+// policiesChain returns the policies of the route groups and the route own ones.
+// The group policies are resolved at each call, so they apply even when added after the route.
+func (re *RouteEntry) policiesChain() []Policy {
+	if re.group == nil {
+		return re.policies
+	}
+	return append(re.group.policiesChain(), re.policies...)
+}
+
+// authorize checks the route policies and responds if the request is denied.
+func (re *RouteEntry) authorize(c *Context) bool {
+	policies := re.policiesChain()
+	if len(policies) == 0 {
+		return true
+	}
+	p := c.User()
+	for _, policy := range policies {
+		if policy.Allow(p, c.Req) {
+			continue
+		}
+		if p == nil {
+			c.app().serveUnauthorized(c)
+		} else {
+			c.app().serveForbidden(c)
+		}
+		return false
+	}
+	return true
+}
+
+// Unauthorized registers the "unauthorized" handler, used when an authentication middleware or a policy denies an anonymous request.
+// The "WWW-Authenticate" header is already set by authentication middlewares when the handler is called.
+func (a *App) Unauthorized(handler Handler) {
+	if a.unauthorizedHandler != nil {
+		panic(`app: "unauthorized" handler set multiple times`)
+	}
+	a.unauthorizedHandler = handler
+}
+
+// Unauthorized registers the "unauthorized" handler, used when an authentication middleware or a policy denies an anonymous request.
+// The "WWW-Authenticate" header is already set by authentication middlewares when the handler is called.
+func Unauthorized(handler Handler) {
+	defaultApp.Unauthorized(handler)
+}
+
+// Forbidden registers the "forbidden" handler, used when a policy denies an authenticated user.
+func (a *App) Forbidden(handler Handler) {
+	if a.forbiddenHandler != nil {
+		panic(`app: "forbidden" handler set multiple times`)
+	}
+	a.forbiddenHandler = handler
+}
+
+// Forbidden registers the "forbidden" handler, used when a policy denies an authenticated user.
+func Forbidden(handler Handler) {
+	defaultApp.Forbidden(handler)
+}
+
+// serveUnauthorized responds with the "unauthorized" handler, or a "401 Unauthorized" problem.
+func (a *App) serveUnauthorized(c *Context) {
+	if a.unauthorizedHandler != nil {
+		a.unauthorizedHandler(c)
+		return
+	}
+	c.Problem(&HTTPError{Status: http.StatusUnauthorized})
+}
+
+// serveForbidden responds with the "forbidden" handler, or a "403 Forbidden" problem.
+func (a *App) serveForbidden(c *Context) {
+	if a.forbiddenHandler != nil {
+		a.forbiddenHandler(c)
+		return
+	}
+	c.Problem(&HTTPError{Status: http.StatusForbidden})
+}
@@ -0,0 +1,97 @@
+package app_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func owner(p *app.Principal, r *http.Request) bool {
+	return p != nil && r.URL.Query().Get("owner") == p.ID
+}
+
+func TestAuthorize(t *testing.T) {
+	a := app.New()
+	a.Forbidden(func(c *app.Context) {
+		c.Status(http.StatusForbidden)
+		c.Text("forbidden")
+	})
+	a.Get("/public", func(c *app.Context) {
+		c.Text("public")
+	})
+	admin := a.Group("/admin").Authorize(app.Require("admin"))
+	admin.Get("/stats", func(c *app.Context) {
+		c.Text("stats")
+	})
+	admin.Group("/billing").Get("/invoices", func(c *app.Context) {
+		c.Text("invoices")
+	}).Authorize(app.PolicyFunc(owner))
+
+	c := apptest.New(t, a, app.BearerTokenFunc(func(_ *app.Context, token string) *app.Principal {
+		switch token {
+		case "alice":
+			return &app.Principal{ID: "alice", Roles: []string{"admin"}}
+		case "bob":
+			return &app.Principal{ID: "bob"}
+		}
+		return nil
+	}))
+	c.Do(authRequest("Bearer bob")).Status(http.StatusNotFound)
+
+	req := authRequest("Bearer alice")
+	req.URL.Path = "/admin/stats"
+	c.Do(req).Status(http.StatusOK).Body("stats")
+
+	req = authRequest("Bearer bob")
+	req.URL.Path = "/admin/stats"
+	c.Do(req).Status(http.StatusForbidden).Body("forbidden")
+
+	req = authRequest("Bearer alice")
+	req.URL.Path = "/admin/billing/invoices"
+	c.Do(req).Status(http.StatusForbidden)
+	req = authRequest("Bearer alice")
+	req.URL.Path = "/admin/billing/invoices"
+	req.URL.RawQuery = "owner=alice"
+	c.Do(req).Status(http.StatusOK).Body("invoices")
+
+	routes := a.Routes()
+	if p := routes[len(routes)-1].Policies; len(p) != 2 || p[0] != "require(admin)" || p[1] != "github.com/gowww/app_test.owner" {
+		t.Errorf("route policies: want [require(admin) github.com/gowww/app_test.owner], got %v", p)
+	}
+}
+
+func TestAuthorizeAnonymous(t *testing.T) {
+	a := app.New()
+	a.Unauthorized(func(c *app.Context) {
+		c.Status(http.StatusUnauthorized)
+		c.Text("log in")
+	})
+	a.Get("/", func(c *app.Context) {
+		c.Text("secret")
+	}).Authorize(app.Require("admin"))
+
+	apptest.New(t, a).Get("/").Status(http.StatusUnauthorized).Body("log in")
+}
+
+func TestAuthorizeGroupLate(t *testing.T) {
+	a := app.New()
+	late := a.Group("/late")
+	late.Group("/sub").Get("/x", func(c *app.Context) {
+		c.Text("x")
+	})
+	late.Get("/y", func(c *app.Context) {
+		c.Text("y")
+	})
+	late.Authorize(app.Require("admin"))
+
+	c := apptest.New(t, a)
+	c.Get("/late/y").Status(http.StatusUnauthorized)
+	c.Get("/late/sub/x").Status(http.StatusUnauthorized)
+	for _, r := range a.Routes() {
+		if r.Path == "/late/y" && (len(r.Policies) != 1 || r.Policies[0] != "require(admin)") {
+			t.Errorf("route policies: want [require(admin)], got %v", r.Policies)
+		}
+	}
+}
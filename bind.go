@@ -0,0 +1,322 @@
+package app
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gowww/check"
+)
+
+// bindMaxMemory is the maximum memory used to parse a multipart form, the rest being stored on disk.
+const bindMaxMemory = 32 << 20 // 32 MB
+
+// defaultBindLimit is the default maximum size of a JSON body read by Context.Bind.
+const defaultBindLimit = 1 << 20 // 1 MB
+
+// bindBodyKey is the errors key used when the request body can't be parsed.
+const bindBodyKey = "body"
+
+// bindTimeLayouts are the layouts tried to parse a time value.
+var bindTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04", "2006-01-02"}
+
+var (
+	typeTime            = reflect.TypeOf(time.Time{})
+	typeDuration        = reflect.TypeOf(time.Duration(0))
+	typeFileHeader      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindLimit sets the maximum size of a JSON body read by Context.Bind (1 MB by default).
+// A larger body is responded with "413 Request Entity Too Large".
+func (a *App) BindLimit(n int64) {
+	if a.bindLimit != 0 {
+		panic("app: bind limit set multiple times")
+	}
+	if n <= 0 {
+		panic("app: bind limit must be positive")
+	}
+	a.bindLimit = n
+}
+
+// BindLimit sets the maximum size of a JSON body read by Context.Bind (1 MB by default).
+// A larger body is responded with "413 Request Entity Too Large".
+func BindLimit(n int64) {
+	defaultApp.BindLimit(n)
+}
+
+// Bind fills the struct pointed by dst with the request data and returns the conversion errors (never nil).
+//
+// Struct fields are bound by tags:
+//
+//	path:"id"	the path parameter
+//	query:"page"	the URL query value
+//	form:"email"	the form value (URL query or urlencoded/multipart body, like Context.FormValue)
+//
+// If the request has a JSON body, it's decoded into dst (with the standard json tags) before the path and query values are bound.
+// A JSON body larger than the limit (see App.BindLimit) is responded with "413 Request Entity Too Large".
+//
+// Supported field types are strings, booleans, numbers, time.Time (RFC 3339 or HTML date inputs), time.Duration, encoding.TextUnmarshaler implementations and slices or pointers of them.
+// A *multipart.FileHeader (or a slice of them) is bound from a multipart form file.
+// Embedded structs are bound too.
+func (c *Context) Bind(dst interface{}) check.Errors {
+	_, errs := c.bind(dst)
+	return errs
+}
+
+// BindCheck binds the request data into dst like Bind and validates the bound values with checker.
+// Conversion and checking errors are merged.
+//
+// For a JSON body, the checker keys are the top-level JSON keys.
+func (c *Context) BindCheck(dst interface{}, checker check.Checker) check.Errors {
+	form, errs := c.bind(dst)
+	errs.Merge(checker.Check(form))
+	return errs
+}
+
+// BadRequestBind binds and checks the request data into dst like BindCheck, and a view name to execute on fail.
+// If you don't provide a view name (empty string), the response will be a JSON errors map.
+//
+// If there are errors, it sets the status to "400 Bad Request" and returns true, allowing you to exit from the handler.
+func (c *Context) BadRequestBind(dst interface{}, checker check.Checker, view string, data ...ViewData) bool {
+	return c.badRequest(c.BindCheck(dst, checker), view, data...)
+}
+
+// bind fills dst and returns all the bound values (used for checking) with conversion errors.
+func (c *Context) bind(dst interface{}) (*multipart.Form, check.Errors) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("app: binding destination must be a pointer to a struct, not %T", dst))
+	}
+	errs := make(check.Errors)
+	form := &multipart.Form{Value: make(map[string][]string)}
+
+	ct, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	isJSON := ct == "application/json" || strings.HasSuffix(ct, "+json")
+	if isJSON {
+		c.bindJSON(dst, form, errs)
+	} else {
+		c.Req.ParseMultipartForm(bindMaxMemory)
+		for k, v := range c.Req.Form {
+			form.Value[k] = v
+		}
+		if c.Req.MultipartForm != nil {
+			form.File = c.Req.MultipartForm.File
+		}
+	}
+
+	query := c.Req.URL.Query()
+	bindStruct(rv.Elem(), errs, func(tag reflect.StructTag) (string, []string, []*multipart.FileHeader) {
+		if key := tag.Get("path"); key != "" {
+			v := c.PathValue(key)
+			form.Value[key] = []string{v}
+			if v == "" {
+				return key, nil, nil
+			}
+			return key, []string{v}, nil
+		}
+		if key := tag.Get("query"); key != "" {
+			form.Value[key] = query[key]
+			return key, query[key], nil
+		}
+		if key := tag.Get("form"); key != "" && !isJSON { // A JSON body is already decoded.
+			var files []*multipart.FileHeader
+			if form.File != nil {
+				files = form.File[key]
+			}
+			return key, form.Value[key], files
+		}
+		return "", nil, nil
+	})
+	return form, errs
+}
+
+// bindJSON decodes the JSON body into dst and keeps its top-level values in form.
+func (c *Context) bindJSON(dst interface{}, form *multipart.Form, errs check.Errors) {
+	limit := c.app().bindLimit
+	if limit == 0 {
+		limit = defaultBindLimit
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Res, c.Req.Body, limit))
+	if int64(len(body)) >= limit && err != nil {
+		panic(&HTTPError{Status: http.StatusRequestEntityTooLarge})
+	}
+	if err != nil {
+		errs.Add(bindBodyKey, &check.Error{Error: check.ErrInvalid})
+		return
+	}
+	c.Req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+	if err = json.Unmarshal(body, dst); err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+			errs.Add(te.Field, &check.Error{Error: conversionErrorID(te.Type)})
+		} else {
+			errs.Add(bindBodyKey, &check.Error{Error: check.ErrInvalid})
+		}
+	}
+	var values map[string]interface{}
+	if json.Unmarshal(body, &values) != nil {
+		return
+	}
+	for k, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case []interface{}:
+			for _, vv := range v {
+				form.Value[k] = append(form.Value[k], fmt.Sprint(vv))
+			}
+		default:
+			form.Value[k] = []string{fmt.Sprint(v)}
+		}
+	}
+}
+
+// bindStruct fills the fields of struct v with the values given by lookup for their tags.
+func bindStruct(v reflect.Value, errs check.Errors, lookup func(reflect.StructTag) (string, []string, []*multipart.FileHeader)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			bindStruct(fv, errs, lookup)
+			continue
+		}
+		if sf.PkgPath != "" { // Unexported field.
+			continue
+		}
+		key, values, files := lookup(sf.Tag)
+		if key == "" {
+			continue
+		}
+		if len(files) > 0 {
+			bindFiles(fv, files)
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := bindValues(fv, values); err != nil {
+			errs.Add(key, &check.Error{Error: conversionErrorID(fv.Type())})
+		}
+	}
+}
+
+// bindFiles sets the multipart files into a *multipart.FileHeader or []*multipart.FileHeader field.
+func bindFiles(v reflect.Value, files []*multipart.FileHeader) {
+	switch {
+	case v.Type() == typeFileHeader:
+		v.Set(reflect.ValueOf(files[0]))
+	case v.Kind() == reflect.Slice && v.Type().Elem() == typeFileHeader:
+		v.Set(reflect.ValueOf(files))
+	}
+}
+
+// bindValues converts and sets values into v.
+// Only the first value is used if v is not a slice.
+func bindValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && !reflect.PtrTo(v.Type()).Implements(typeTextUnmarshaler) {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := bindValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return bindValue(v, values[0])
+}
+
+// bindValue converts and sets s into v.
+func bindValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := bindValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	switch v.Type() {
+	case typeTime:
+		for _, layout := range bindTimeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("app: cannot parse %q as time", s)
+	case typeDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(typeTextUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "on" { // HTML checkbox default value.
+			s = "true"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		panic(fmt.Errorf("app: cannot bind into type %v", v.Type()))
+	}
+	return nil
+}
+
+// conversionErrorID returns the checking error used when a value can't be converted to type t.
+func conversionErrorID(t reflect.Type) *check.ErrorID {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t == typeDuration {
+		return check.ErrInvalid
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return check.ErrNotInteger
+	case reflect.Float32, reflect.Float64:
+		return check.ErrNotNumber
+	}
+	return check.ErrInvalid
+}
@@ -0,0 +1,83 @@
+package app_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+	"github.com/gowww/check"
+)
+
+type bindUser struct {
+	ID       int       `path:"id" json:"-"`
+	Page     uint      `query:"page" json:"-"`
+	Email    string    `form:"email" json:"email"`
+	Admin    bool      `form:"admin" json:"admin"`
+	Birthday time.Time `form:"birthday" json:"birthday"`
+	Tags     []string  `form:"tag" json:"tags"`
+	Age      *int      `form:"age" json:"age"`
+}
+
+var bindChecker = check.Checker{
+	"email": {check.Required, check.Email},
+}
+
+func TestBind(t *testing.T) {
+	var u bindUser
+	a := app.New()
+	a.Post("/users/:id", func(c *app.Context) {
+		u = bindUser{}
+		if c.BadRequestBind(&u, bindChecker, "") {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	c := apptest.New(t, a)
+
+	c.PostForm("/users/42?page=3", url.Values{
+		"email":    {"me@example.com"},
+		"admin":    {"on"},
+		"birthday": {"2000-01-02"},
+		"tag":      {"a", "b"},
+		"age":      {"20"},
+	}).Status(http.StatusNoContent)
+	if u.ID != 42 || u.Page != 3 || u.Email != "me@example.com" || !u.Admin || u.Birthday.Day() != 2 || len(u.Tags) != 2 || u.Age == nil || *u.Age != 20 {
+		t.Errorf("form binding: got %+v", u)
+	}
+
+	c.PostForm("/users/42?page=x", url.Values{"email": {"wrong"}, "age": {"old"}}).
+		Status(http.StatusBadRequest).
+		CheckError("page", "notInteger").
+		CheckError("age", "notInteger").
+		CheckError("email", "notEmail")
+
+	c.PostJSON("/users/7", map[string]interface{}{"email": "me@example.com", "tags": []string{"x"}}).
+		Status(http.StatusNoContent)
+	if u.ID != 7 || u.Email != "me@example.com" || len(u.Tags) != 1 {
+		t.Errorf("JSON binding: got %+v", u)
+	}
+
+	c.PostJSON("/users/7", map[string]interface{}{"age": "old"}).
+		Status(http.StatusBadRequest).
+		CheckError("age", "notInteger").
+		CheckError("email", "required")
+}
+
+func TestBindLimit(t *testing.T) {
+	a := app.New()
+	a.BindLimit(32)
+	a.Post("/users", func(c *app.Context) {
+		var u bindUser
+		c.Bind(&u)
+		c.Text(u.Email)
+	})
+	c := apptest.New(t, a)
+	c.PostJSON("/users", map[string]string{"email": "me@example.com"}).Status(http.StatusOK).Body("me@example.com")
+	c.PostJSON("/users", map[string]string{"email": strings.Repeat("a", 32) + "@example.com"}).
+		Status(http.StatusRequestEntityTooLarge).
+		Header("Content-Type", "application/problem+json")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// certValidity is the validity duration of a generated development certificate.
+const certValidity = 365 * 24 * time.Hour
+
+// cert generates a self-signed certificate for local development.
+func cert() {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		log.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"gowww development"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+	}
+	for _, h := range strings.Split(flagCertHosts, ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = writePEM(flagCertFile, "CERTIFICATE", der, 0644); err != nil {
+		log.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = writePEM(flagCertKeyFile, "PRIVATE KEY", keyDER, 0600); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Certificate written to %s and %s", flagCertFile, flagCertKeyFile)
+	log.Printf("Run your app with: gowww watch -- -cert %s -key %s", flagCertFile, flagCertKeyFile)
+}
+
+// writePEM writes a PEM encoded block to file.
+func writePEM(file, typ string, b []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err = pem.Encode(f, &pem.Block{Type: typ, Bytes: b}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -22,6 +22,9 @@ const (
 var (
 	flagBuildDocker  bool
 	flagBuildName    string
+	flagCertFile     string
+	flagCertHosts    string
+	flagCertKeyFile  string
 	flagKeygenNumber int
 
 	watcher     *fsnotify.Watcher
@@ -38,14 +41,33 @@ func main() {
 		Bool(&flagBuildDocker, "docker", false, `Use Docker's "golang:latest" image to build for Linux.`).
 		String(&flagBuildName, "name", getwd(false), "The file name used for build.")
 
+	cli.Command("cert", cert, "Generate a self-signed TLS certificate for development.").
+		String(&flagCertFile, "cert", "cert.pem", "The file name used for the certificate.").
+		String(&flagCertKeyFile, "key", "key.pem", "The file name used for the private key.").
+		String(&flagCertHosts, "hosts", "localhost,127.0.0.1,::1", "The comma-separated hosts and IPs covered by the certificate.")
+
 	cli.Command("keygen", keygen, "Print a 32 btes generated key.").
 		Int(&flagKeygenNumber, "n", 1, "The number of generated keys.")
 
+	cli.Command("routes", routes, "Print the routes table of app.")
+
 	cli.Command("watch", watch, "Detect changes and rerun app.")
 
 	cli.Parse()
 
-	watch()
+	// A command without flags is not run by cli.Parse.
+	switch cli.Arg(0) {
+	case "build":
+		build()
+	case "cert":
+		cert()
+	case "keygen":
+		keygen()
+	case "routes":
+		routes()
+	default:
+		watch()
+	}
 }
 
 func run() {
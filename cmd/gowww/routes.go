@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+)
+
+// appRoutes is the routes table of an app, as printed by an app run with flag -routes (one per line for multiple apps).
+type appRoutes struct {
+	Address string      `json:"address"`
+	Routes  []routeInfo `json:"routes"`
+}
+
+// routeInfo is a route as printed by an app run with flag -routes.
+type routeInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Name        string   `json:"name"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+	Policies    []string `json:"policies"`
+}
+
+// routes builds the app and prints its routes table, for each app of the program.
+func routes() {
+	if err := buildGo(); err != nil {
+		os.Exit(1)
+	}
+	cmd := exec.Command("./"+buildName(), "-routes")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var aa []appRoutes
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var ar appRoutes
+		if err = dec.Decode(&ar); err != nil {
+			log.Fatalf("Could not read routes: %v", err)
+		}
+		aa = append(aa, ar)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, ar := range aa {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "App on %s\n", ar.Address)
+		fmt.Fprintln(tw, "METHOD\tPATH\tNAME\tHANDLER\tMIDDLEWARES\tPOLICIES")
+		for _, r := range ar.Routes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, r.Name, r.Handler, strings.Join(r.Middlewares, ", "), strings.Join(r.Policies, "; "))
+		}
+	}
+	tw.Flush()
+}
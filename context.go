@@ -9,11 +9,11 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"runtime/debug"
 
 	"golang.org/x/text/language"
 
 	"github.com/gowww/check"
-	"github.com/gowww/fatal"
 	"github.com/gowww/i18n"
 	"github.com/gowww/router"
 )
@@ -24,15 +24,81 @@ type Context struct {
 	Req *http.Request
 }
 
+type contextKey int
+
+// Context keys
+const (
+	contextKeyApp contextKey = iota
+	contextKeyViewHook
+	contextKeyCorrelationID
+	contextKeyError
+	contextKeyErrorStack
+	contextKeyDebug
+	contextKeySession
+	contextKeyFlash
+	contextKeyCSRF
+	contextKeyCSRFSecret
+	contextKeyUser
+	contextKeyRouteMatches
+	contextKeyWriter
+)
+
+// appHandle stores the app and the correlation ID in the request's context, before recovering so they are available to the error handler.
+func appHandle(a *App, h http.Handler) http.Handler {
+	return Handler(func(c *Context) {
+		c.Set(contextKeyApp, a)
+		c.Set(contextKeyCorrelationID, correlationID(c.Req))
+		c.Set(contextKeyRouteMatches, new(routeMatches))
+		h.ServeHTTP(c.Res, c.Req)
+	})
+}
+
 // contextHandle wraps the router for setting headers and deferring their write.
-func contextHandle(h http.Handler) http.Handler {
+// It also recovers from panics to respond with the error handler (see App.serveError).
+func contextHandle(a *App, h http.Handler) http.Handler {
 	return Handler(func(c *Context) {
 		cw := &contextWriter{ResponseWriter: c.Res}
+		c.Set(contextKeyWriter, cw)
+		session := new(sessionHolder)
+		c.Set(contextKeySession, session)
+		flash := new(flashHolder)
+		c.Set(contextKeyFlash, flash)
+		c.Set(contextKeyCSRFSecret, new(csrfHolder))
+		cw.beforeWrite(func() { a.saveSession(cw, session) })
+		cw.beforeWrite(func() { a.saveFlashes(cw, flash) })
 		defer func() {
+			cw.commit()
 			if cw.status != 0 {
 				c.Res.WriteHeader(cw.status)
 			}
 		}()
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			var err error
+			var stack []byte
+			switch v := rec.(type) {
+			case *HTTPError:
+				err = v
+			case error:
+				err, stack = fmt.Errorf("%w", v), debug.Stack()
+			default:
+				err, stack = fmt.Errorf("%v", v), debug.Stack()
+			}
+			if cw.written {
+				log.Printf("Serving %s: %v (response already written)\n%s", c.Req.RemoteAddr, err, stack)
+				return
+			}
+			a.serveError(cw, c.Req, err, stack)
+		}()
+		if !production {
+			c.Set(contextKeyDebug, new(debugState))
+		}
 		cw.Header().Set("Cache-Control", "no-cache")
 		cw.Header().Set("Connection", "keep-alive")
 		h.ServeHTTP(cw, c.Req)
@@ -41,9 +107,29 @@ func contextHandle(h http.Handler) http.Handler {
 
 // logWriter keeps the status code from WriteHeader to allow setting headers after a Context.Status call.
 // Required when using Context.Status with Context.JSON, for example.
+// Functions registered with beforeWrite are called once, just before the header is written.
 type contextWriter struct {
 	http.ResponseWriter
-	status int
+	status    int
+	written   bool // written tells if the body has started to be written.
+	hooks     []func()
+	committed bool
+}
+
+// beforeWrite registers f to be called just before the header is written.
+func (cw *contextWriter) beforeWrite(f func()) {
+	cw.hooks = append(cw.hooks, f)
+}
+
+// commit calls the hooks registered with beforeWrite, once.
+func (cw *contextWriter) commit() {
+	if cw.committed {
+		return
+	}
+	cw.committed = true
+	for _, f := range cw.hooks {
+		f()
+	}
 }
 
 func (cw *contextWriter) WriteHeader(status int) {
@@ -51,10 +137,12 @@ func (cw *contextWriter) WriteHeader(status int) {
 }
 
 func (cw *contextWriter) Write(b []byte) (int, error) {
+	cw.commit()
 	if cw.status != 0 {
 		cw.ResponseWriter.WriteHeader(cw.status)
 		cw.status = 0
 	}
+	cw.written = true
 	return cw.ResponseWriter.Write(b)
 }
 
@@ -71,6 +159,7 @@ func (cw *contextWriter) CloseNotify() <-chan bool {
 // Flush implements the http.Flusher interface.
 // Nothing is done if Flush is not implemented by an upstream response writer.
 func (cw *contextWriter) Flush() {
+	cw.commit()
 	f, ok := cw.ResponseWriter.(http.Flusher)
 	if ok {
 		f.Flush()
@@ -80,6 +169,7 @@ func (cw *contextWriter) Flush() {
 // Hijack implements the http.Hijacker interface.
 // Error http.ErrNotSupported is returned if Hijack is not implemented by an upstream response writer.
 func (cw *contextWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.commit()
 	h, ok := cw.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, http.ErrNotSupported
@@ -97,6 +187,20 @@ func (cw *contextWriter) Push(target string, opts *http.PushOptions) error {
 	return p.Push(target, opts)
 }
 
+// NewContext returns a context for the app, allowing to use its helpers outside a route handler.
+func (a *App) NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	return &Context{Res: w, Req: r.WithContext(context.WithValue(r.Context(), contextKeyApp, a))}
+}
+
+// app returns the app serving the request.
+// If the request doesn't come from an app handler, the default app is used.
+func (c *Context) app() *App {
+	if a, ok := c.Get(contextKeyApp).(*App); ok {
+		return a
+	}
+	return defaultApp
+}
+
 // Get returns a context value.
 func (c *Context) Get(key interface{}) interface{} {
 	return c.Req.Context().Value(key)
@@ -105,6 +209,9 @@ func (c *Context) Get(key interface{}) interface{} {
 // Set sets a context value.
 func (c *Context) Set(key, val interface{}) {
 	c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), key, val))
+	if ds := c.debug(); ds != nil && ds.req != nil {
+		ds.req = c.Req
+	}
 }
 
 // PathValue returns the value of path parameter.
@@ -150,12 +257,24 @@ func (c *Context) Status(code int) *Context {
 
 // View writes the response with a rendered view.
 // This data is always part of the rendering:
+//
 //	.	the GlobalViewData
 //	.c	the Context
-//	.errors	the translated errors map
+//	.errors	the translated errors map (the flashed ones if not set)
+//	.flashes	the flashed messages
+//	.form	the flashed form values
 func (c *Context) View(name string, data ...ViewData) {
 	mdata := mergeViewData(data)
 	mdata["c"] = c
+	if _, ok := mdata["flashes"]; !ok {
+		mdata["flashes"] = c.Flashes()
+	}
+	if _, ok := mdata["form"]; !ok {
+		mdata["form"] = c.FlashedForm()
+	}
+	if _, ok := mdata["errors"]; !ok && c.FlashedErrors() != nil {
+		mdata["errors"] = c.FlashedErrors()
+	}
 	switch errs := mdata["errors"].(type) {
 	case check.TranslatedErrors:
 		break
@@ -164,10 +283,20 @@ func (c *Context) View(name string, data ...ViewData) {
 	default:
 		mdata["errors"] = make(check.TranslatedErrors)
 	}
-	err := views.ExecuteTemplate(c, name, mdata)
+	if hook, ok := c.Get(contextKeyViewHook).(ViewHook); ok {
+		hook(name, ViewData(mdata))
+	}
+	ds := c.debug()
+	if ds != nil {
+		ds.viewName, ds.viewData = name, ViewData(mdata)
+	}
+	err := c.app().views.ExecuteTemplate(c, name, mdata)
 	if err != nil {
 		c.Panic(err)
 	}
+	if ds != nil {
+		ds.viewName, ds.viewData = "", nil
+	}
 }
 
 // JSON writes the response with a marshalled JSON.
@@ -203,7 +332,11 @@ func (c *Context) TErrors(errs check.Errors) check.TranslatedErrors {
 //
 // If the check fails, it sets the status to "400 Bad Request" and returns true, allowing you to exit from the handler.
 func (c *Context) BadRequest(checker check.Checker, view string, data ...ViewData) bool {
-	errs := c.Check(checker)
+	return c.badRequest(c.Check(checker), view, data...)
+}
+
+// badRequest responds with errs like BadRequest, if there are errors.
+func (c *Context) badRequest(errs check.Errors, view string, data ...ViewData) bool {
 	if errs.Empty() {
 		return false
 	}
@@ -226,42 +359,18 @@ func (c *Context) Redirect(url string, status int) {
 // If multiple cookies match the given name, only one cookie value will be returned.
 // If the secret key is set for app, value will be decrypted before returning.
 // If cookie is not found or the decryption fails, an empty string is returned.
+// A value encrypted with an old secret key is still accepted: use RefreshCookie to re-issue it with the primary key.
+// Use CookieWith for a plain or signed cookie.
 func (c *Context) Cookie(name string) string {
-	ck, _ := c.Req.Cookie(name)
-	if ck == nil {
-		return ""
-	}
-	if encrypter == nil {
-		return ck.Value
-	}
-	v, err := encrypter.DecryptBase64([]byte(ck.Value))
-	if err != nil {
-		c.DeleteCookie(name)
-		return ""
-	}
-	return string(v)
+	return c.CookieWith(name, CookieDefault)
 }
 
 // SetCookie sets a cookie to the response.
-// If the secret key is set for app, value will be encrypted.
-// If the app is not in a production environment, the "secure" flag will be set to false.
+// If the secret key is set for app, value will be encrypted (and the cookie HttpOnly).
+// The app cookie defaults are used for the attributes not set (see App.Cookies).
+// Use SetCookieWith for a plain or signed cookie.
 func (c *Context) SetCookie(cookie *http.Cookie) {
-	if !production {
-		cookie.Secure = false
-	}
-	if encrypter != nil {
-		v, err := encrypter.EncryptBase64([]byte(cookie.Value))
-		if err != nil {
-			c.Panic(err)
-		}
-		cookie.Value = string(v)
-	}
-	http.SetCookie(c.Res, cookie)
-}
-
-// DeleteCookie removes a cookie from the client.
-func (c *Context) DeleteCookie(name string) {
-	http.SetCookie(c.Res, &http.Cookie{Name: name, MaxAge: -1})
+	c.SetCookieWith(cookie, CookieDefault)
 }
 
 // translator returns the request translator.
@@ -318,11 +427,7 @@ func (c *Context) Push(target string, opts *http.PushOptions) {
 
 // NotFound responds with the "not found" handler.
 func (c *Context) NotFound() {
-	if rt.NotFoundHandler != nil {
-		rt.NotFoundHandler.ServeHTTP(c.Res, c.Req)
-	} else {
-		http.NotFound(c.Res, c.Req)
-	}
+	c.app().serveNotFound(c.Res, c.Req)
 }
 
 // Log logs the message with the client address.
@@ -332,10 +437,19 @@ func (c *Context) Log(msg string) {
 
 // Panic logs error with stack trace and responds with the error handler if set.
 func (c *Context) Panic(err error) {
-	panic(fmt.Errorf("Failed serving %s: %v", c.Req.RemoteAddr, err))
+	panic(fmt.Errorf("Failed serving %s: %w", c.Req.RemoteAddr, err))
 }
 
-// Error returns the error value stored in request's context after a recovering or a Context.Error call.
+// Error returns the error stored in request's context after a recovering, or nil.
+// A recovered error is wrapped so it can be inspected with errors.Is and errors.As.
+// For an HTTPError (panicked or returned by an ErrHandler), the *HTTPError is returned.
 func (c *Context) Error() error {
-	return fmt.Errorf("%v", fatal.Error(c.Req))
+	err, _ := c.Get(contextKeyError).(error)
+	return err
+}
+
+// ErrorStack returns the stack trace of the recovered panic, or nil.
+func (c *Context) ErrorStack() []byte {
+	stack, _ := c.Get(contextKeyErrorStack).([]byte)
+	return stack
 }
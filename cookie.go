@@ -0,0 +1,276 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookie name prefixes enforced by browsers.
+const (
+	cookiePrefixSecure = "__Secure-" // cookiePrefixSecure requires the Secure attribute.
+	cookiePrefixHost   = "__Host-"   // cookiePrefixHost requires the Secure attribute, path "/" and no domain.
+)
+
+// CookieOptions are the default attributes of the cookies set by the app.
+type CookieOptions struct {
+	Path     string        // Path is used for cookies without path ("/" by default).
+	Domain   string        // Domain is used for cookies without domain (except "__Host-" cookies).
+	SameSite http.SameSite // SameSite is used for cookies without SameSite attribute (Lax by default).
+
+	// Insecure stops setting the Secure attribute in production, for an app not served over HTTPS.
+	// Cookies named with the "__Secure-" or "__Host-" prefix can't be used then.
+	Insecure bool
+}
+
+// Cookies sets the default attributes of the cookies set by the app, including sessions and flashes.
+//
+// In production, cookies are always Secure (unless the options are Insecure).
+// Outside production, they are never Secure (so they work over plain HTTP), except cookies named with the "__Secure-" or "__Host-" prefix, which browsers accept on localhost.
+func (a *App) Cookies(o *CookieOptions) {
+	if a.cookieOptions != nil {
+		panic("app: cookie options set multiple times")
+	}
+	a.cookieOptions = o
+}
+
+// Cookies sets the default attributes of the cookies set by the app, including sessions and flashes.
+//
+// In production, cookies are always Secure (unless the options are Insecure).
+// Outside production, they are never Secure (so they work over plain HTTP), except cookies named with the "__Secure-" or "__Host-" prefix, which browsers accept on localhost.
+func Cookies(o *CookieOptions) {
+	defaultApp.Cookies(o)
+}
+
+// setCookie sets cookie to w, with the app cookie defaults.
+// It panics if the requirements of a prefixed cookie name are not met.
+func (a *App) setCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	var o CookieOptions
+	if a.cookieOptions != nil {
+		o = *a.cookieOptions
+	}
+	host := strings.HasPrefix(cookie.Name, cookiePrefixHost)
+	prefixed := host || strings.HasPrefix(cookie.Name, cookiePrefixSecure)
+	if cookie.Path == "" {
+		cookie.Path = o.Path
+		if cookie.Path == "" || host {
+			cookie.Path = "/"
+		}
+	}
+	if cookie.Domain == "" && !host {
+		cookie.Domain = o.Domain
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = o.SameSite
+		if cookie.SameSite == 0 {
+			cookie.SameSite = http.SameSiteLaxMode
+		}
+	}
+	cookie.Secure = (prefixed || production) && !o.Insecure
+	if prefixed && !cookie.Secure {
+		panic(fmt.Errorf("app: cookie %q must be secure, but cookie options are insecure", cookie.Name))
+	}
+	if host && (cookie.Path != "/" || cookie.Domain != "") {
+		panic(fmt.Errorf("app: cookie %q must have path \"/\" and no domain", cookie.Name))
+	}
+	http.SetCookie(w, cookie)
+}
+
+// DeleteCookie removes the named cookie from the client, with the app cookie defaults (see App.Cookies).
+// Use DeleteCookieWith for a cookie set with another path or domain.
+func (c *Context) DeleteCookie(name string) {
+	c.app().deleteCookie(c.Res, &http.Cookie{Name: name})
+}
+
+// DeleteCookieWith removes a cookie from the client.
+// Its path and domain must be the ones the cookie has been set with (the app cookie defaults are used if not set).
+func (c *Context) DeleteCookieWith(cookie *http.Cookie) {
+	c.app().deleteCookie(c.Res, cookie)
+}
+
+// deleteCookie removes a cookie from the client, with the app cookie defaults.
+func (a *App) deleteCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	ck := *cookie
+	ck.Value = ""
+	ck.Expires = time.Unix(0, 0)
+	ck.MaxAge = -1
+	a.setCookie(w, &ck)
+}
+
+// A CookieMode is the protection of a cookie value.
+type CookieMode int
+
+// Cookie modes.
+const (
+	CookieDefault   CookieMode = iota // CookieDefault encrypts the value if the secret key is set, like Context.SetCookie.
+	CookiePlain                       // CookiePlain keeps the value as is, readable and modifiable by the client (and by scripts if not HttpOnly).
+	CookieSigned                      // CookieSigned keeps the value readable, followed by its signature so it can't be modified.
+	CookieEncrypted                   // CookieEncrypted encrypts the value, always HttpOnly as scripts can't read it.
+)
+
+// errCookieSignature is returned when a signed cookie has an invalid signature.
+var errCookieSignature = errors.New("invalid cookie signature")
+
+// SetCookieWith sets a cookie to the response, with its value protected by mode.
+// A signed or encrypted cookie needs the app secret key.
+// The app cookie defaults are used for the attributes not set (see App.Cookies).
+func (c *Context) SetCookieWith(cookie *http.Cookie, mode CookieMode) {
+	mode = c.cookieMode(mode)
+	switch mode {
+	case CookieSigned:
+		cookie.Value = cookie.Value + "." + base64.RawURLEncoding.EncodeToString(c.keyring("signed").sign(cookieMessage(cookie.Name, cookie.Value)))
+	case CookieEncrypted:
+		v, err := c.keyring("encrypted").EncryptBase64([]byte(cookie.Value))
+		if err != nil {
+			c.Panic(err)
+		}
+		cookie.Value = string(v)
+		cookie.HttpOnly = true
+	}
+	c.app().setCookie(c.Res, cookie)
+}
+
+// CookieWith returns the value of the named cookie, protected by mode.
+// If cookie is not found, or its signature or decryption fails, an empty string is returned (and an invalid cookie is removed, with the app cookie defaults).
+// For a cookie set with another path or domain, use RefreshCookie so an invalid one is removed with its attributes.
+// A value signed or encrypted with an old secret key is still accepted: use RefreshCookie to re-issue it with the primary key.
+func (c *Context) CookieWith(name string, mode CookieMode) string {
+	v, _ := c.cookieValue(&http.Cookie{Name: name}, mode)
+	return v
+}
+
+// RefreshCookie returns the value of the cookie named like cookie, protected by mode, like CookieWith.
+// The other fields of cookie must be the attributes the cookie has been set with: if the value has been signed or encrypted with an old secret key, the cookie is re-issued with them and the primary key, and an invalid cookie is removed with them.
+func (c *Context) RefreshCookie(cookie *http.Cookie, mode CookieMode) string {
+	v, stale := c.cookieValue(cookie, mode)
+	if stale {
+		ck := *cookie
+		ck.Value = v
+		c.SetCookieWith(&ck, mode)
+	}
+	return v
+}
+
+// cookieValue returns the value of the cookie named like cookie, protected by mode, and tells if it has been signed or encrypted with an old secret key.
+// An invalid cookie is removed, with the attributes of cookie.
+func (c *Context) cookieValue(cookie *http.Cookie, mode CookieMode) (string, bool) {
+	ck, _ := c.Req.Cookie(cookie.Name)
+	if ck == nil {
+		return "", false
+	}
+	mode = c.cookieMode(mode)
+	var v []byte
+	var stale bool
+	var err error
+	switch mode {
+	case CookiePlain:
+		return ck.Value, false
+	case CookieSigned:
+		v, stale, err = c.keyring("signed").verifyCookie(cookie.Name, ck.Value)
+	case CookieEncrypted:
+		v, stale, err = c.keyring("encrypted").decryptBase64([]byte(ck.Value))
+	}
+	if err != nil {
+		c.DeleteCookieWith(cookie)
+		return "", false
+	}
+	return string(v), stale
+}
+
+// cookieMode resolves CookieDefault.
+func (c *Context) cookieMode(mode CookieMode) CookieMode {
+	if mode != CookieDefault {
+		return mode
+	}
+	if c.app().encrypter != nil {
+		return CookieEncrypted
+	}
+	return CookiePlain
+}
+
+// keyring returns the app keyring, needed for a kind of cookie.
+func (c *Context) keyring(kind string) *Keyring {
+	if c.app().encrypter == nil {
+		panic("app: no secret key set, " + kind + " cookie can't be used")
+	}
+	return c.app().encrypter
+}
+
+// cookieMessage returns the signed message for a cookie, binding the value to the cookie name.
+func cookieMessage(name, value string) []byte {
+	return []byte(name + "=" + value)
+}
+
+// verifyCookie returns the value of a signed cookie, and tells if it has been signed with an old key.
+func (k *Keyring) verifyCookie(name, signed string) ([]byte, bool, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i == -1 {
+		return nil, false, errCookieSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if err != nil {
+		return nil, false, errCookieSignature
+	}
+	ok, stale := k.verify(cookieMessage(name, signed[:i]), sig)
+	if !ok {
+		return nil, false, errCookieSignature
+	}
+	return []byte(signed[:i]), stale, nil
+}
+
+// cookieJSON is the payload of a cookie set by Context.SetCookieJSON.
+type cookieJSON struct {
+	Value   json.RawMessage `json:"v"`
+	Expires int64           `json:"e"` // Expires is a Unix time.
+}
+
+// SetCookieJSON sets a cookie holding v encoded in JSON, protected by mode, and valid for maxAge.
+// The expiry is kept in the value, so an expired cookie is refused even if the client keeps it.
+func (c *Context) SetCookieJSON(name string, v interface{}, maxAge time.Duration, mode CookieMode) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.Panic(err)
+	}
+	expires := time.Now().Add(maxAge)
+	if b, err = json.Marshal(cookieJSON{b, expires.Unix()}); err != nil {
+		c.Panic(err)
+	}
+	c.SetCookieWith(&http.Cookie{
+		Name:    name,
+		Value:   base64.RawURLEncoding.EncodeToString(b),
+		Expires: expires,
+	}, mode)
+}
+
+// CookieJSON decodes into v the JSON value of the named cookie, protected by mode (see SetCookieJSON).
+// It tells if the cookie is found, valid and not expired.
+// If the value has been signed or encrypted with an old secret key, the cookie is re-issued with the primary key, keeping its expiry (and the app cookie defaults).
+func (c *Context) CookieJSON(name string, v interface{}, mode CookieMode) bool {
+	s, stale := c.cookieValue(&http.Cookie{Name: name}, mode)
+	if s == "" {
+		return false
+	}
+	var payload cookieJSON
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &payload)
+	}
+	if err == nil && time.Now().Unix() >= payload.Expires {
+		err = errors.New("cookie expired")
+	}
+	if err == nil {
+		err = json.Unmarshal(payload.Value, v)
+	}
+	if err != nil {
+		c.DeleteCookie(name) // Set with the app cookie defaults by SetCookieJSON.
+		return false
+	}
+	if stale {
+		c.SetCookieWith(&http.Cookie{Name: name, Value: s, Expires: time.Unix(payload.Expires, 0)}, mode)
+	}
+	return true
+}
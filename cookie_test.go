@@ -0,0 +1,205 @@
+package app_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func responseCookie(t *testing.T, r *apptest.Response, name string) *http.Cookie {
+	for _, ck := range r.Recorder.Result().Cookies() {
+		if ck.Name == name {
+			return ck
+		}
+	}
+	t.Fatalf("cookie %q not set", name)
+	return nil
+}
+
+func TestCookieModes(t *testing.T) {
+	a := app.New()
+	a.Secret(newKey)
+	modes := map[string]app.CookieMode{"plain": app.CookiePlain, "signed": app.CookieSigned, "encrypted": app.CookieEncrypted}
+	a.Post("/", func(c *app.Context) {
+		for name, mode := range modes {
+			c.SetCookieWith(&http.Cookie{Name: name, Value: "dark", Path: "/"}, mode)
+		}
+	})
+	a.Get("/:mode", func(c *app.Context) {
+		c.Text(c.CookieWith(c.PathValue("mode"), modes[c.PathValue("mode")]))
+	})
+
+	c := apptest.New(t, a)
+	r := c.PostForm("/", url.Values{})
+	if ck := responseCookie(t, r, "plain"); ck.Value != "dark" || ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode {
+		t.Errorf("plain cookie: want readable value with SameSite=Lax, got %v", ck)
+	}
+	if ck := responseCookie(t, r, "signed"); !strings.HasPrefix(ck.Value, "dark.") || ck.HttpOnly {
+		t.Errorf("signed cookie: want readable value, got %v", ck)
+	}
+	if ck := responseCookie(t, r, "encrypted"); strings.Contains(ck.Value, "dark") || !ck.HttpOnly {
+		t.Errorf("encrypted cookie: want HttpOnly encrypted value, got %v", ck)
+	}
+	for name := range modes {
+		c.Get("/" + name).Body("dark")
+	}
+
+	c.SetCookie(&http.Cookie{Name: "signed", Value: "light." + strings.SplitN(responseCookie(t, r, "signed").Value, ".", 2)[1]})
+	c.Get("/signed").Body("")
+	c.SetCookie(&http.Cookie{Name: "plain", Value: "light"})
+	c.Get("/plain").Body("light")
+}
+
+func TestSignedCookieRotation(t *testing.T) {
+	theme := &http.Cookie{Name: "theme", Path: "/prefs", Domain: "example.com", MaxAge: 3600, SameSite: http.SameSiteStrictMode}
+	handler := func(c *app.Context) {
+		switch c.Req.Method {
+		case http.MethodPost:
+			ck := *theme
+			ck.Value = "dark"
+			c.SetCookieWith(&ck, app.CookieSigned)
+			c.SetCookieJSON("prefs", "dark", time.Hour, app.CookieSigned)
+		case http.MethodDelete:
+			c.Text(c.CookieWith("theme", app.CookieSigned))
+		default:
+			var prefs string
+			c.CookieJSON("prefs", &prefs, app.CookieSigned)
+			c.Text(c.RefreshCookie(theme, app.CookieSigned) + " " + prefs)
+		}
+	}
+	old := app.New()
+	old.Secret(oldKey)
+	old.Any("/prefs", handler)
+	rotated := app.New()
+	rotated.Secret(newKey, oldKey)
+	rotated.Any("/prefs", handler)
+	fresh := app.New()
+	fresh.Secret(newKey)
+	fresh.Any("/prefs", handler)
+
+	r := apptest.New(t, old).PostForm("/prefs", url.Values{})
+	cookies := r.Recorder.Result().Cookies()
+	prefs := responseCookie(t, r, "prefs")
+	c := apptest.New(t, rotated)
+	for _, ck := range cookies {
+		c.SetCookie(&http.Cookie{Name: ck.Name, Value: ck.Value})
+	}
+	if r = c.Delete("/prefs").Body("dark"); len(r.Recorder.Result().Cookies()) > 0 {
+		t.Errorf("stale cookie read with CookieWith: want not re-issued, got %v", r.Recorder.Result().Cookies())
+	}
+	r = c.Get("/prefs").Body("dark dark")
+	ck := responseCookie(t, r, "theme")
+	if ck.Path != theme.Path || ck.Domain != theme.Domain || ck.MaxAge != theme.MaxAge || ck.SameSite != theme.SameSite {
+		t.Errorf("refreshed cookie: want attributes of %v, got %v", theme, ck)
+	}
+	if ck = responseCookie(t, r, "prefs"); !ck.Expires.Equal(prefs.Expires) {
+		t.Errorf("refreshed JSON cookie: want expiry %v, got %v", prefs.Expires, ck.Expires)
+	}
+	cookies = r.Recorder.Result().Cookies()
+	c = apptest.New(t, fresh)
+	for _, ck := range cookies {
+		c.SetCookie(&http.Cookie{Name: ck.Name, Value: ck.Value})
+	}
+	c.Get("/prefs").Body("dark dark")
+}
+
+func TestCookieJSON(t *testing.T) {
+	type prefs struct {
+		Theme string `json:"theme"`
+		Size  int    `json:"size"`
+	}
+	a := app.New()
+	a.Secret(newKey)
+	a.Post("/", func(c *app.Context) {
+		c.SetCookieJSON("prefs", prefs{"dark", 14}, time.Hour, app.CookieSigned)
+		c.SetCookieJSON("expired", prefs{"dark", 14}, -time.Hour, app.CookieEncrypted)
+	})
+	a.Get("/", func(c *app.Context) {
+		var p prefs
+		if !c.CookieJSON("prefs", &p, app.CookieSigned) {
+			c.Text("no prefs")
+			return
+		}
+		if c.CookieJSON("expired", &p, app.CookieEncrypted) {
+			t.Error("expired JSON cookie: want refused")
+		}
+		c.JSON(p)
+	})
+
+	c := apptest.New(t, a)
+	c.Get("/").Body("no prefs")
+	expired := responseCookie(t, c.PostForm("/", url.Values{}), "expired")
+	c.SetCookie(&http.Cookie{Name: expired.Name, Value: expired.Value}) // Kept by the client anyway.
+	c.Get("/").JSON(prefs{"dark", 14})
+}
+
+func TestCookieOptions(t *testing.T) {
+	a := app.New()
+	a.Cookies(&app.CookieOptions{Path: "/app", Domain: "example.com", SameSite: http.SameSiteStrictMode})
+	a.Post("/app", func(c *app.Context) {
+		c.SetCookieWith(&http.Cookie{Name: "theme", Value: "dark"}, app.CookiePlain)
+		c.SetCookieWith(&http.Cookie{Name: "__Host-id", Value: "1"}, app.CookiePlain)
+	})
+	a.Delete("/app", func(c *app.Context) {
+		c.DeleteCookieWith(&http.Cookie{Name: "admin", Path: "/app/admin"})
+		c.DeleteCookie("theme")
+	})
+	a.Get("/app/host", func(c *app.Context) {
+		defer func() {
+			if recover() == nil {
+				t.Error("__Host- cookie with a path: want panic")
+			}
+		}()
+		c.SetCookieWith(&http.Cookie{Name: "__Host-id", Value: "1", Path: "/app"}, app.CookiePlain)
+	})
+
+	c := apptest.New(t, a)
+	r := c.PostForm("/app", url.Values{})
+	if ck := responseCookie(t, r, "theme"); ck.Path != "/app" || ck.Domain != "example.com" || ck.SameSite != http.SameSiteStrictMode || ck.Secure {
+		t.Errorf("cookie defaults: want path /app, domain example.com, SameSite=Strict and not secure outside production, got %v", ck)
+	}
+	if ck := responseCookie(t, r, "__Host-id"); ck.Path != "/" || ck.Domain != "" || !ck.Secure {
+		t.Errorf("__Host- cookie: want secure with path / and no domain, got %v", ck)
+	}
+	r = c.Delete("/app")
+	if ck := responseCookie(t, r, "admin"); ck.Path != "/app/admin" || ck.Domain != "example.com" || ck.MaxAge != -1 {
+		t.Errorf("deleted cookie: want path /app/admin, domain example.com and max age -1, got %v", ck)
+	}
+	if ck := responseCookie(t, r, "theme"); ck.Path != "/app" || ck.Domain != "example.com" || ck.MaxAge != -1 {
+		t.Errorf("deleted cookie by name: want path /app, domain example.com and max age -1, got %v", ck)
+	}
+	c.Get("/app/host")
+}
+
+func TestCookieOptionsInsecure(t *testing.T) {
+	a := app.New()
+	a.Cookies(&app.CookieOptions{Insecure: true})
+	a.Get("/", func(c *app.Context) {
+		defer func() {
+			if recover() == nil {
+				t.Error("__Secure- cookie with insecure options: want panic")
+			}
+		}()
+		c.SetCookieWith(&http.Cookie{Name: "__Secure-id", Value: "1"}, app.CookiePlain)
+	})
+	apptest.New(t, a).Get("/")
+}
+
+func TestCookieInvalidPath(t *testing.T) {
+	a := app.New()
+	a.Secret(newKey)
+	a.Get("/admin/filter", func(c *app.Context) {
+		c.Text(c.RefreshCookie(&http.Cookie{Name: "filter", Path: "/admin"}, app.CookieSigned))
+	})
+
+	c := apptest.New(t, a)
+	c.SetCookie(&http.Cookie{Name: "filter", Value: "tampered.c2ln"})
+	if ck := responseCookie(t, c.Get("/admin/filter").Body(""), "filter"); ck.Path != "/admin" || ck.MaxAge != -1 {
+		t.Errorf("invalid cookie: want removed with path /admin, got %v", ck)
+	}
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions are the options of the cross-origin resource sharing.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make requests: exact (like "https://app.example.com"), with a wildcard subdomain (like "https://*.example.com") or "*" for all.
+	AllowedOrigins []string
+
+	// AllowOriginFunc tells if an origin is allowed, when it's not one of AllowedOrigins.
+	AllowOriginFunc func(origin string, r *http.Request) bool
+
+	AllowedMethods   []string      // AllowedMethods are the methods allowed by preflights (the methods having a route for the path by default).
+	AllowedHeaders   []string      // AllowedHeaders are the request headers allowed by preflights (the requested ones by default).
+	ExposedHeaders   []string      // ExposedHeaders are the response headers the client can read, besides the simple ones.
+	AllowCredentials bool          // AllowCredentials allows requests with cookies or authorization. It can't be used with the "*" origin.
+	MaxAge           time.Duration // MaxAge is how long the client can cache a preflight response.
+}
+
+// CORS sets the cross-origin resource sharing options for all routes.
+// Group options (see RouterGroup.CORS) take precedence.
+func (a *App) CORS(o *CORSOptions) {
+	if a.corsOptions != nil {
+		panic("app: CORS options set multiple times")
+	}
+	a.corsOptions = o.validate()
+}
+
+// CORS sets the cross-origin resource sharing options for all routes.
+// Group options (see RouterGroup.CORS) take precedence.
+func CORS(o *CORSOptions) {
+	defaultApp.CORS(o)
+}
+
+// CORS sets the cross-origin resource sharing options for the group (and its subgroups).
+// It must be called before making the group routes.
+//
+// Preflight requests are answered automatically for the group paths, before any middleware, with the methods having a route for the path.
+func (rg *RouterGroup) CORS(o *CORSOptions) *RouterGroup {
+	if rg.corsOptions != nil {
+		panic("app: group CORS options set multiple times")
+	}
+	rg.corsOptions = o.validate()
+	return rg
+}
+
+// corsConfig returns the CORS options of the group or its nearest parent, or nil.
+func (rg *RouterGroup) corsConfig() *CORSOptions {
+	if rg.corsOptions != nil || rg.parent == nil {
+		return rg.corsOptions
+	}
+	return rg.parent.corsConfig()
+}
+
+// validate returns the options if they allow origins, or panics.
+// Credentials can't be allowed for all origins, as the CORS specification forbids it.
+func (o *CORSOptions) validate() *CORSOptions {
+	if o == nil || len(o.AllowedOrigins) == 0 && o.AllowOriginFunc == nil {
+		panic("app: CORS options allow no origin")
+	}
+	if o.AllowCredentials && o.allowAll() {
+		panic(`app: CORS options allow credentials for the "*" origin`)
+	}
+	return o
+}
+
+// allowOrigin tells if origin is allowed.
+func (o *CORSOptions) allowOrigin(origin string, r *http.Request) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if i := strings.Index(allowed, "://*."); i != -1 {
+			scheme, domain := allowed[:i+3], allowed[i+4:] // domain keeps its leading dot.
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, domain) && len(origin) > len(scheme)+len(domain) {
+				return true
+			}
+		}
+	}
+	return o.AllowOriginFunc != nil && o.AllowOriginFunc(origin, r)
+}
+
+// allowAll tells if all origins are allowed.
+func (o *CORSOptions) allowAll() bool {
+	return containsString(o.AllowedOrigins, "*")
+}
+
+// corsConfig returns the CORS options of the route matching the request path (for method if possible), or the app ones.
+func (a *App) corsConfig(r *http.Request, method string) *CORSOptions {
+	re := a.matchRoute(r, method)
+	if re == nil {
+		for m := range a.probes {
+			if re = a.matchRoute(r, m); re != nil {
+				break
+			}
+		}
+	}
+	if re != nil && re.corsOptions != nil {
+		return re.corsOptions
+	}
+	return a.corsOptions
+}
+
+// corsHandle handles the cross-origin requests, before the app middlewares so their responses (like errors) can also be read by the client.
+func corsHandle(a *App, h http.Handler) http.Handler {
+	return Handler(func(c *Context) {
+		if a.corsOptions == nil && !a.corsRoutes {
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+		method := c.Req.Method
+		preflight := method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			method = c.Req.Header.Get("Access-Control-Request-Method")
+		}
+		o := a.corsConfig(c.Req, method)
+		if o == nil {
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+		header := c.Res.Header()
+		header.Add("Vary", "Origin") // The response depends on the origin, even without one.
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !o.allowOrigin(origin, c.Req) {
+			if preflight {
+				c.Res.WriteHeader(http.StatusNoContent) // Without CORS headers, the client refuses the request.
+				return
+			}
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+		if o.allowAll() {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if o.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if len(o.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(o.ExposedHeaders, ", "))
+			}
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+
+		allowed := a.allowedMethods(c.Req)
+		if len(allowed) == 0 { // The path doesn't exist.
+			h.ServeHTTP(c.Res, c.Req)
+			return
+		}
+		if len(o.AllowedMethods) > 0 {
+			allowed = o.AllowedMethods
+		}
+		header.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+		if len(o.AllowedHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(o.AllowedHeaders, ", "))
+		} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if o.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge/time.Second)))
+		}
+		c.Res.WriteHeader(http.StatusNoContent)
+	})
+}
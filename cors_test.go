@@ -0,0 +1,145 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func corsRequest(method, path, origin string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	return req
+}
+
+func preflightRequest(path, origin, method string) *http.Request {
+	req := corsRequest(http.MethodOptions, path, origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, Authorization")
+	return req
+}
+
+func TestCORSGroup(t *testing.T) {
+	a := app.New()
+	a.Get("/home", func(c *app.Context) {
+		c.Text("home")
+	})
+	api := a.Group("/api", app.BearerToken(map[string]*app.Principal{"t0k3n": {ID: "ci"}})).CORS(&app.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Total"},
+		MaxAge:           10 * time.Minute,
+	})
+	api.Get("/users", func(c *app.Context) {
+		c.Text("users")
+	})
+	api.Put("/users", func(c *app.Context) {
+		c.Text("updated")
+	})
+
+	c := apptest.New(t, a)
+	c.Do(preflightRequest("/api/users", "https://app.example.com", http.MethodPut)).
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "https://app.example.com").
+		Header("Access-Control-Allow-Credentials", "true").
+		Header("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS, PUT").
+		Header("Access-Control-Allow-Headers", "Content-Type, Authorization").
+		Header("Access-Control-Max-Age", "600")
+
+	c.Do(preflightRequest("/api/users", "https://eu.example.org", http.MethodGet)).
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "https://eu.example.org")
+
+	c.Do(preflightRequest("/api/users", "https://evil.com", http.MethodGet)).
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "")
+
+	r := c.Do(corsRequest(http.MethodGet, "/api/users", "https://app.example.com")).
+		Status(http.StatusUnauthorized). // Errors of the group middlewares are readable by the client.
+		Header("Access-Control-Allow-Origin", "https://app.example.com").
+		Header("Access-Control-Expose-Headers", "X-Total")
+	if vary := strings.Join(r.Recorder.Result().Header.Values("Vary"), ", "); !strings.Contains(vary, "Origin") {
+		t.Errorf("Vary header: want Origin, got %q", vary)
+	}
+
+	req := corsRequest(http.MethodGet, "/api/users", "https://app.example.com")
+	req.Header.Set("Authorization", "Bearer t0k3n")
+	c.Do(req).Status(http.StatusOK).Body("users")
+
+	c.Do(corsRequest(http.MethodGet, "/home", "https://app.example.com")).
+		Status(http.StatusOK).
+		Header("Access-Control-Allow-Origin", "")
+}
+
+func TestCORSApp(t *testing.T) {
+	a := app.New()
+	a.CORS(&app.CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"Content-Type"},
+	})
+	a.Post("/items", func(c *app.Context) {
+		c.Text("created")
+	})
+	a.Group("/partners").CORS(&app.CORSOptions{
+		AllowOriginFunc: func(origin string, _ *http.Request) bool {
+			return origin == "https://partner.com"
+		},
+	}).Get("/feed", func(c *app.Context) {
+		c.Text("feed")
+	})
+
+	c := apptest.New(t, a)
+	c.Do(preflightRequest("/items", "https://any.com", http.MethodPost)).
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "*").
+		Header("Access-Control-Allow-Methods", "OPTIONS, POST").
+		Header("Access-Control-Allow-Headers", "Content-Type")
+	c.Do(corsRequest(http.MethodPost, "/items", "https://any.com")).
+		Status(http.StatusOK).
+		Header("Access-Control-Allow-Origin", "*")
+	c.Do(preflightRequest("/unknown", "https://any.com", http.MethodGet)).Status(http.StatusNotFound)
+
+	c.Do(corsRequest(http.MethodGet, "/partners/feed", "https://partner.com")).
+		Header("Access-Control-Allow-Origin", "https://partner.com")
+	c.Do(corsRequest(http.MethodGet, "/partners/feed", "https://any.com")).
+		Header("Access-Control-Allow-Origin", "")
+}
+
+func TestCORSCredentialsAll(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("credentials for all origins: want panic")
+		}
+	}()
+	app.New().CORS(&app.CORSOptions{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+}
+
+func TestCORSTrailingSlash(t *testing.T) {
+	a := app.New()
+	a.CORS(&app.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+	a.Get("/users", func(c *app.Context) {
+		c.Text("users " + c.Req.URL.Path)
+	})
+
+	c := apptest.New(t, a)
+	c.FollowRedirects = false
+	c.Do(corsRequest(http.MethodGet, "/users/", "https://app.example.com")).
+		Status(http.StatusMovedPermanently).
+		Header("Location", "/users")
+	c.Do(corsRequest(http.MethodHead, "/users/", "")).
+		Status(http.StatusMovedPermanently).
+		Header("Location", "/users")
+	c.Do(corsRequest(http.MethodGet, "/users", "https://app.example.com")).
+		Status(http.StatusOK).
+		Body("users /users")
+}
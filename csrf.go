@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+// CSRF defaults.
+const (
+	defaultCSRFFieldName  = "csrf_token"
+	defaultCSRFHeaderName = "X-CSRF-Token"
+	defaultCSRFCookieName = "csrf"
+	csrfSessionKey        = "_csrf"
+)
+
+// CSRFOptions are the options of the CSRF protection.
+type CSRFOptions struct {
+	FieldName      string  // FieldName is the form field holding the token ("csrf_token" by default).
+	HeaderName     string  // HeaderName is the request header holding the token, for fetch/XHR requests ("X-CSRF-Token" by default).
+	CookieName     string  // CookieName is the encrypted cookie holding the token secret of clients without a session ("csrf" by default).
+	FailureHandler Handler // FailureHandler responds when the token is missing or invalid (a "403 Forbidden" problem by default).
+}
+
+// CSRF returns a middleware protecting the routes against cross-site request forgery.
+// Requests with an unsafe method (not GET, HEAD, OPTIONS or TRACE) must send the token given by Context.CSRFToken, in the form field or the header set by the options.
+//
+// The token secret is kept in the session (see Context.Session) once it's saved, or in an encrypted cookie for anonymous clients, so rendering a form doesn't store anything server-side.
+// The token is sent encrypted with the app secret key, differently for each rendering.
+// The check is made just before the route handler, so a route or a group can opt out with RouteEntry.SkipCSRF or RouterGroup.SkipCSRF, even if the middleware is set for the whole app.
+func CSRF(o *CSRFOptions) Middleware {
+	var opts CSRFOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = defaultCSRFFieldName
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = defaultCSRFHeaderName
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = defaultCSRFCookieName
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyCSRF, &opts)))
+		})
+	}
+}
+
+// SkipCSRF opts the route out of the CSRF protection.
+func (re *RouteEntry) SkipCSRF() *RouteEntry {
+	re.skipCSRF = true
+	return re
+}
+
+// SkipCSRF opts the group (and its subgroups) out of the CSRF protection, like for webhook endpoints.
+func (rg *RouterGroup) SkipCSRF() *RouterGroup {
+	rg.skipCSRF = true
+	return rg
+}
+
+// skipsCSRF tells if the group or one of its parents opts out of the CSRF protection.
+func (rg *RouterGroup) skipsCSRF() bool {
+	return rg.skipCSRF || rg.parent != nil && rg.parent.skipsCSRF()
+}
+
+// csrfOptions returns the CSRF options of the request, or the defaults if it's not protected.
+func (c *Context) csrfOptions() *CSRFOptions {
+	if o, ok := c.Get(contextKeyCSRF).(*CSRFOptions); ok {
+		return o
+	}
+	return &CSRFOptions{FieldName: defaultCSRFFieldName, HeaderName: defaultCSRFHeaderName, CookieName: defaultCSRFCookieName}
+}
+
+// A csrfHolder keeps the CSRF secret of a request without a session, shared by all its contexts.
+type csrfHolder struct {
+	secret string
+}
+
+// csrfSecret returns the CSRF secret, generated if needed.
+// It's kept in the session if the session is saved anyway, or in an encrypted cookie otherwise.
+func (c *Context) csrfSecret() string {
+	s := c.Session()
+	if secret := s.String(csrfSessionKey); secret != "" {
+		return secret
+	}
+	if !s.fresh || s.modified || s.renewed {
+		secret := randomHex(32)
+		s.Set(csrfSessionKey, secret)
+		return secret
+	}
+	holder, ok := c.Get(contextKeyCSRFSecret).(*csrfHolder)
+	if !ok {
+		panic("app: CSRF token used outside an app handler")
+	}
+	if holder.secret == "" {
+		name := c.csrfOptions().CookieName
+		holder.secret = c.RefreshCookie(&http.Cookie{Name: name}, CookieEncrypted)
+		if holder.secret == "" {
+			holder.secret = randomHex(32)
+			c.SetCookieWith(&http.Cookie{Name: name, Value: holder.secret}, CookieEncrypted)
+		}
+	}
+	return holder.secret
+}
+
+// CSRFToken returns the token to send with an unsafe request, in the form field or the header (see CSRF).
+// It's different on each call so it can't be guessed from a compressed response (BREACH attack).
+func (c *Context) CSRFToken() string {
+	encrypter := c.app().encrypter
+	if encrypter == nil {
+		panic("app: no secret key set, CSRF token can't be encrypted")
+	}
+	token, err := encrypter.EncryptBase64([]byte(c.csrfSecret()))
+	if err != nil {
+		c.Panic(err)
+	}
+	return string(token)
+}
+
+// checkCSRF tells if the request has a valid CSRF token or doesn't need one.
+func (c *Context) checkCSRF(o *CSRFOptions) bool {
+	switch c.Req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	token := c.Req.Header.Get(o.HeaderName)
+	if token == "" {
+		token = c.Req.PostFormValue(o.FieldName)
+	}
+	if token == "" || c.app().encrypter == nil {
+		return false
+	}
+	secret, err := c.app().encrypter.DecryptBase64([]byte(token))
+	if err != nil {
+		return false
+	}
+	for _, want := range []string{c.Session().String(csrfSessionKey), c.CookieWith(o.CookieName, CookieEncrypted)} {
+		if want != "" && subtle.ConstantTimeCompare(secret, []byte(want)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// serveCSRFFailure responds to a request with an invalid CSRF token.
+func (c *Context) serveCSRFFailure(o *CSRFOptions) {
+	if o.FailureHandler != nil {
+		o.FailureHandler(c)
+		return
+	}
+	c.Problem(&HTTPError{Status: http.StatusForbidden, Detail: "Missing or invalid CSRF token."})
+}
+
+// csrfField returns the hidden input holding the CSRF token, for view function "csrf".
+func csrfField(c *Context) template.HTML {
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, template.HTMLEscapeString(c.csrfOptions().FieldName), c.CSRFToken()))
+}
+
+// csrfMeta returns the meta tags holding the CSRF token and header name for fetch/XHR requests, for view function "csrfMeta".
+func csrfMeta(c *Context) template.HTML {
+	return template.HTML(fmt.Sprintf(`<meta name="csrf-token" content="%s"><meta name="csrf-header" content="%s">`, c.CSRFToken(), template.HTMLEscapeString(c.csrfOptions().HeaderName)))
+}
@@ -0,0 +1,90 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func TestCSRF(t *testing.T) {
+	a := app.New()
+	a.Secret("ab7d8ee1c25e4f6a8d8b4a9c27f1f1e3")
+	a.Get("/form", func(c *app.Context) {
+		c.Text(c.CSRFToken())
+	})
+	a.Post("/form", func(c *app.Context) {
+		c.Text("saved")
+	})
+	a.Post("/stripe", func(c *app.Context) {
+		c.Text("hooked")
+	}).SkipCSRF()
+	a.Group("/hooks").SkipCSRF().Group("/github").Post("/push", func(c *app.Context) {
+		c.Text("pushed")
+	})
+
+	c := apptest.New(t, a, app.CSRF(&app.CSRFOptions{
+		FailureHandler: func(c *app.Context) {
+			c.Status(http.StatusForbidden)
+			c.Text("forged")
+		},
+	}))
+	c.PostForm("/form", nil).Status(http.StatusForbidden).Body("forged")
+	token := c.Get("/form").Recorder.Body.String()
+	if token2 := c.Get("/form").Recorder.Body.String(); token2 == token {
+		t.Error("CSRF token: want a different value on each call")
+	}
+	c.PostForm("/form", url.Values{"csrf_token": {token}}).Status(http.StatusOK).Body("saved")
+	c.PostForm("/form", url.Values{"csrf_token": {"forged"}}).Status(http.StatusForbidden)
+
+	req := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader("{}"))
+	req.Header.Set("X-CSRF-Token", token)
+	c.Do(req).Status(http.StatusOK)
+
+	c.PostForm("/stripe", nil).Status(http.StatusOK).Body("hooked")
+	c.PostForm("/hooks/github/push", nil).Status(http.StatusOK).Body("pushed")
+
+	other := apptest.New(t, a, app.CSRF(nil))
+	other.PostForm("/form", url.Values{"csrf_token": {token}}).
+		Status(http.StatusForbidden).
+		Header("Content-Type", "application/problem+json")
+}
+
+func TestCSRFAnonymous(t *testing.T) {
+	a := app.New()
+	a.Secret("ab7d8ee1c25e4f6a8d8b4a9c27f1f1e3")
+	store := app.NewFileSessionStore(t.TempDir())
+	a.Sessions(&app.SessionOptions{Store: store})
+	a.Get("/form", func(c *app.Context) {
+		c.Text(c.CSRFToken())
+	})
+	a.Post("/login", func(c *app.Context) {
+		c.Session().Set("user", "ann")
+		c.Text(c.CSRFToken())
+	})
+
+	c := apptest.New(t, a, app.CSRF(nil))
+	r := c.Get("/form")
+	token := r.Recorder.Body.String()
+	for _, ck := range r.Recorder.Result().Cookies() {
+		if ck.Name == "session" {
+			t.Errorf("anonymous token: want no session, got cookie %v", ck)
+		}
+	}
+	if ck := responseCookie(t, r, "csrf"); !ck.HttpOnly {
+		t.Errorf("CSRF cookie: want HttpOnly, got %v", ck)
+	}
+	if token2 := c.Get("/form").Recorder.Body.String(); token2 == token {
+		t.Error("CSRF token: want a different value on each call")
+	}
+	r = c.PostForm("/login", url.Values{"csrf_token": {token}}).Status(http.StatusOK)
+	responseCookie(t, r, "session")
+
+	c.SetCookie(&http.Cookie{Name: "csrf", Value: "", MaxAge: -1})
+	c.PostForm("/login", url.Values{"csrf_token": {token}}).Status(http.StatusForbidden)
+	c.PostForm("/login", url.Values{"csrf_token": {r.Recorder.Body.String()}}).Status(http.StatusOK)
+}
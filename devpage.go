@@ -0,0 +1,312 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// devPageSourceLines is the number of source lines shown around each stack frame line.
+const devPageSourceLines = 3
+
+// A debugState keeps what the developer error page shows about a request, as it's served.
+// It's only set in development.
+type debugState struct {
+	req      *http.Request // req is the latest request seen downstream, with path parameters and context values.
+	route    *RouteEntry
+	viewName string // viewName is the view being rendered, if any.
+	viewData ViewData
+}
+
+// debug returns the debug state of the request, or nil in production.
+func (c *Context) debug() *debugState {
+	ds, _ := c.Get(contextKeyDebug).(*debugState)
+	return ds
+}
+
+// A devPageFrame is an annotated stack frame.
+type devPageFrame struct {
+	Func   string
+	File   string
+	Line   int
+	Source []devPageLine
+}
+
+// A devPageLine is a source line of a stack frame.
+type devPageLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// A devPageValue is a named value.
+type devPageValue struct {
+	Name  string
+	Value string
+}
+
+// A devPageSection is a titled list of values.
+type devPageSection struct {
+	Title  string
+	Values []devPageValue
+}
+
+// serveDevError responds with the developer error page for err recovered with stack.
+func serveDevError(w http.ResponseWriter, r *http.Request, err error, stack []byte) {
+	c := &Context{Res: w, Req: r}
+	data := map[string]interface{}{
+		"Error":         err.Error(),
+		"Method":        r.Method,
+		"CorrelationID": c.CorrelationID(),
+		"URL":           r.URL.String(),
+		"Stack":         parseStack(stack),
+	}
+	if cause := errors.Unwrap(err); cause != nil { // The panic value is an error.
+		for errors.Unwrap(cause) != nil {
+			cause = errors.Unwrap(cause)
+		}
+		data["Type"] = fmt.Sprintf("%T", cause)
+	}
+
+	req := r
+	if ds := c.debug(); ds != nil {
+		if ds.req != nil {
+			req = ds.req
+		}
+		if ds.route != nil {
+			data["Route"] = ds.route.method + " " + ds.route.path
+			data["RouteName"] = ds.route.name
+			data["Handler"] = handlerName(ds.route.handler)
+			data["PathValues"] = pathValues(req, ds.route.path)
+		}
+		if ds.viewName != "" {
+			data["ViewName"] = ds.viewName
+			var vv []devPageValue
+			for k, v := range ds.viewData {
+				if k != "c" {
+					vv = append(vv, devPageValue{k, fmt.Sprintf("%#v", v)})
+				}
+			}
+			data["ViewData"] = sortValues(vv)
+		}
+	}
+
+	req.ParseForm()
+	var form []devPageValue
+	for k, v := range req.Form {
+		form = append(form, devPageValue{k, strings.Join(v, ", ")})
+	}
+
+	var headers []devPageValue
+	for k, v := range req.Header {
+		headers = append(headers, devPageValue{k, strings.Join(v, ", ")})
+	}
+
+	var cookies []devPageValue
+	for _, ck := range req.Cookies() {
+		cookies = append(cookies, devPageValue{ck.Name, (&Context{Res: w, Req: req}).devCookie(ck)})
+	}
+	data["Sections"] = []devPageSection{
+		{"Form", sortValues(form)},
+		{"Headers", sortValues(headers)},
+		{"Cookies", sortValues(cookies)},
+		{"Context values", contextValues(req.Context())},
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	devPageTemplate.Execute(w, data)
+}
+
+// devCookie returns the cookie value, decrypted if the app has a secret.
+func (c *Context) devCookie(ck *http.Cookie) string {
+	encrypter := c.app().encrypter
+	if encrypter == nil {
+		return ck.Value
+	}
+	v, err := encrypter.DecryptBase64([]byte(ck.Value))
+	if err != nil {
+		return ck.Value + " (not decryptable)"
+	}
+	return string(v)
+}
+
+// pathValues returns the values of the named parameters of route path.
+func pathValues(r *http.Request, path string) []devPageValue {
+	c := &Context{Req: r}
+	var vv []devPageValue
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if i == len(parts)-1 && part == "" {
+			if v := c.PathValue("*"); v != "" {
+				vv = append(vv, devPageValue{"*", v})
+			}
+			continue
+		}
+		if len(part) < 2 || part[0] != ':' {
+			continue
+		}
+		name := part[1:]
+		if sep := strings.IndexByte(name, ':'); sep != -1 {
+			name = name[:sep]
+		}
+		if name != "" {
+			vv = append(vv, devPageValue{name, c.PathValue(name)})
+		}
+	}
+	return vv
+}
+
+// contextValues returns the values stored in ctx, walking its parents.
+// As contexts can't be iterated, their unexported fields are read with reflection.
+func contextValues(ctx context.Context) []devPageValue {
+	var vv []devPageValue
+	v := reflect.ValueOf(ctx)
+	for v.IsValid() {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			break
+		}
+		if key, val := v.FieldByName("key"), v.FieldByName("val"); key.IsValid() && val.IsValid() && !isAppContextKey(key) {
+			vv = append(vv, devPageValue{fmt.Sprintf("%v (%s)", key, key.Elem().Type()), fmt.Sprintf("%v", val)})
+		}
+		v = v.FieldByName("Context")
+	}
+	return vv
+}
+
+// isAppContextKey tells if key holds a context key of this package, not shown on the page.
+func isAppContextKey(key reflect.Value) bool {
+	key = key.Elem()
+	return key.IsValid() && key.Type() == reflect.TypeOf(contextKey(0))
+}
+
+// parseStack returns the frames of a stack trace from runtime/debug.Stack, with their source lines.
+func parseStack(stack []byte) []devPageFrame {
+	var frames []devPageFrame
+	sc := bufio.NewScanner(bytes.NewReader(stack))
+	var fn string
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "\t") {
+			fn = line
+			continue
+		}
+		loc := strings.TrimSpace(line)
+		if i := strings.LastIndex(loc, " +0x"); i != -1 {
+			loc = loc[:i]
+		}
+		i := strings.LastIndexByte(loc, ':')
+		if i == -1 {
+			continue
+		}
+		n, err := strconv.Atoi(loc[i+1:])
+		if err != nil {
+			continue
+		}
+		frame := devPageFrame{Func: fn, File: loc[:i], Line: n}
+		if strings.HasPrefix(fn, "runtime/debug.Stack(") || strings.HasPrefix(fn, "panic(") {
+			frames = frames[:0] // Only keep frames from the panic.
+			continue
+		}
+		frame.Source = sourceLines(frame.File, n)
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// sourceLines returns the lines of file around line n, or nil if the file can't be read.
+func sourceLines(file string, n int) []devPageLine {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(b), "\n")
+	var sl []devPageLine
+	for i := n - devPageSourceLines; i <= n+devPageSourceLines; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		sl = append(sl, devPageLine{Number: i, Text: lines[i-1], Current: i == n})
+	}
+	return sl
+}
+
+// sortValues sorts vv by name.
+func sortValues(vv []devPageValue) []devPageValue {
+	sort.Slice(vv, func(i, j int) bool { return vv[i].Name < vv[j].Name })
+	return vv
+}
+
+var devPageTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Error}}</title>
+<style>
+body { margin: 0; font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; }
+header { padding: 1.5em 2em; background: #c0392b; color: #fff; }
+header h1 { margin: 0; font-size: 1.4em; word-wrap: break-word; }
+header p { margin: .5em 0 0; opacity: .8; }
+section { padding: 0 2em; }
+h2 { margin: 1.5em 0 .5em; font-size: 1.1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: .3em .6em; border-bottom: 1px solid #eee; text-align: left; vertical-align: top; font-family: Menlo, Consolas, monospace; font-size: 12px; word-break: break-all; }
+th { width: 20%; color: #555; }
+.frame { margin-bottom: 1em; }
+.frame p { margin: 0; font-family: Menlo, Consolas, monospace; font-size: 12px; }
+.frame .file { color: #777; }
+pre { margin: .3em 0 0; padding: .5em 0; background: #f7f7f7; font-size: 12px; overflow-x: auto; }
+pre span { display: block; padding: 0 .6em; }
+pre span.current { background: #fdd; }
+pre i { display: inline-block; width: 4em; color: #999; font-style: normal; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Error}}</h1>
+<p>{{.Method}} {{.URL}}{{with .Type}} · {{.}}{{end}} · {{.CorrelationID}}</p>
+</header>
+{{with .Route}}<section>
+<h2>Route</h2>
+<table>
+<tr><th>Pattern</th><td>{{.}}</td></tr>
+{{with $.RouteName}}<tr><th>Name</th><td>{{.}}</td></tr>{{end}}
+<tr><th>Handler</th><td>{{$.Handler}}</td></tr>
+{{range $.PathValues}}<tr><th>:{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}
+</table>
+</section>{{end}}
+{{with .ViewName}}<section>
+<h2>View "{{.}}"</h2>
+<table>
+{{range $.ViewData}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}
+</table>
+</section>{{end}}
+<section>
+<h2>Stack trace</h2>
+{{range .Stack}}<div class="frame">
+<p>{{.Func}}</p>
+<p class="file">{{.File}}:{{.Line}}</p>
+{{with .Source}}<pre>{{range .}}<span{{if .Current}} class="current"{{end}}><i>{{.Number}}</i>{{.Text}}</span>{{end}}</pre>{{end}}
+</div>{{end}}
+</section>
+{{range .Sections}}{{if .Values}}<section>
+<h2>{{.Title}}</h2>
+<table>
+{{range .Values}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}
+</table>
+</section>{{end}}{{end}}
+</body>
+</html>
+`))
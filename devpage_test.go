@@ -0,0 +1,38 @@
+package app_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func TestDevErrorPage(t *testing.T) {
+	a := app.New()
+	a.Get("/users/:id", func(c *app.Context) {
+		c.Set("tenant", "acme")
+		panic("boom")
+	}).Name("user")
+
+	c := apptest.New(t, a)
+	c.SetHeader("Accept", "text/html")
+	c.SetHeader("X-Test", "header value")
+	c.SetCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	c.Get("/users/42?tab=posts").
+		Status(http.StatusInternalServerError).
+		Header("Content-Type", "text/html; charset=utf-8").
+		BodyContains("<h1>boom</h1>").
+		BodyContains("GET /users/:id").
+		BodyContains("<th>:id</th><td>42</td>").
+		BodyContains("<th>tab</th><td>posts</td>").
+		BodyContains("header value").
+		BodyContains("<th>theme</th><td>dark</td>").
+		BodyContains("acme").
+		BodyContains(`panic(&#34;boom&#34;)`)
+
+	c.SetHeader("Accept", "application/json")
+	c.Get("/users/42").
+		Status(http.StatusInternalServerError).
+		Header("Content-Type", "application/problem+json")
+}
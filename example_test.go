@@ -1,8 +1,10 @@
 package app_test
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 
 	"github.com/gowww/app"
 	"github.com/gowww/check"
@@ -66,3 +68,35 @@ func ExampleContext_BadRequest() {
 		c.Status(http.StatusCreated)
 	})
 }
+
+func ExampleNew() {
+	admin := app.New()
+	admin.SetAddress(":8081")
+	admin.Get("/", func(c *app.Context) {
+		c.Text("Admin")
+	})
+	go admin.Run()
+
+	app.Get("/", func(c *app.Context) {
+		c.Text("Public")
+	})
+	app.Run()
+}
+
+func ExampleApp_Handler() {
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		c.Text("Hello")
+	})
+
+	srv := httptest.NewServer(a.Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res.Body.Close()
+	fmt.Println(res.StatusCode)
+	// Output: 200
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gowww/check"
+)
+
+// flashCookieName is the name of the cookie keeping the flashes until the next request.
+const flashCookieName = "flash"
+
+// A Flash is a message kept for the next request of the client, typically shown after a redirect.
+type Flash struct {
+	Kind    string `json:"kind"` // Kind is the message category, like "success" or "error".
+	Message string `json:"message"`
+}
+
+// flashData is the content of the flash cookie.
+type flashData struct {
+	Flashes []Flash                `json:"flashes,omitempty"`
+	Errors  check.TranslatedErrors `json:"errors,omitempty"`
+	Form    url.Values             `json:"form,omitempty"`
+}
+
+// A flashHolder keeps the flashes of a request, shared by all its contexts.
+type flashHolder struct {
+	in   *flashData // in are the flashes received, nil until read.
+	read bool
+	out  *flashData // out are the flashes to send.
+}
+
+// Flash keeps a message for the next request of the client.
+// Flashes are stored in a cookie, encrypted with the app secret key, and are read once.
+func (c *Context) Flash(kind, msg string) {
+	out := c.flashOut()
+	out.Flashes = append(out.Flashes, Flash{kind, msg})
+}
+
+// FlashErrors keeps the checking errors (translated) and the submitted form values for the next request of the client.
+// After a redirect, the view rendered for the next request receives them as .errors and .form, so a form can be re-rendered with them.
+//
+// Only the form fields listed are kept if any.
+// Otherwise, all fields are kept except the CSRF token and the password fields (with "password" in their name).
+// If the flash cookie gets too large for browsers, the form values are dropped.
+func (c *Context) FlashErrors(errs check.Errors, fields ...string) {
+	out := c.flashOut()
+	out.Errors = c.TErrors(errs)
+	c.Req.ParseForm()
+	out.Form = make(url.Values)
+	for field, values := range c.Req.PostForm {
+		if len(fields) > 0 && containsString(fields, field) || len(fields) == 0 && !c.sensitiveField(field) {
+			out.Form[field] = values
+		}
+	}
+}
+
+// sensitiveField tells if the form field must not be flashed: the CSRF token or a password.
+func (c *Context) sensitiveField(field string) bool {
+	return field == c.csrfOptions().FieldName || strings.Contains(strings.ToLower(field), "password")
+}
+
+// Flashes returns the messages flashed by the previous request and removes them from the client.
+// They are also given to views as .flashes.
+func (c *Context) Flashes() []Flash {
+	if in := c.flashIn(); in != nil {
+		return in.Flashes
+	}
+	return nil
+}
+
+// FlashedErrors returns the checking errors flashed by the previous request, or nil.
+func (c *Context) FlashedErrors() check.TranslatedErrors {
+	if in := c.flashIn(); in != nil {
+		return in.Errors
+	}
+	return nil
+}
+
+// FlashedForm returns the form values flashed by the previous request, or nil.
+func (c *Context) FlashedForm() url.Values {
+	if in := c.flashIn(); in != nil {
+		return in.Form
+	}
+	return nil
+}
+
+// flashOut returns the flashes to send, to be filled.
+func (c *Context) flashOut() *flashData {
+	if c.app().encrypter == nil {
+		panic("app: no secret key set, flashes can't be encrypted")
+	}
+	holder, ok := c.Get(contextKeyFlash).(*flashHolder)
+	if !ok {
+		panic("app: flash used outside an app handler")
+	}
+	if holder.out == nil {
+		holder.out = new(flashData)
+	}
+	return holder.out
+}
+
+// flashIn returns the flashes received from the client, or nil.
+// Outside an app handler, they are only decoded (not removed).
+func (c *Context) flashIn() *flashData {
+	holder, ok := c.Get(contextKeyFlash).(*flashHolder)
+	if ok && holder.read {
+		return holder.in
+	}
+	var in *flashData
+	if ck, _ := c.Req.Cookie(flashCookieName); ck != nil && c.app().encrypter != nil {
+		in = new(flashData) // An invalid cookie gives no flashes but is removed too.
+		if b, err := c.app().encrypter.DecryptBase64([]byte(ck.Value)); err == nil {
+			json.Unmarshal(b, in)
+		}
+	}
+	if ok {
+		holder.in, holder.read = in, true
+	}
+	return in
+}
+
+// saveFlashes sets the flash cookie with the flashes to send, or removes it if the received flashes have been read.
+// It's called just before the response header is written.
+func (a *App) saveFlashes(w http.ResponseWriter, holder *flashHolder) {
+	if holder.out == nil {
+		if holder.read && holder.in != nil {
+			a.deleteCookie(w, &http.Cookie{Name: flashCookieName})
+		}
+		return
+	}
+	b, err := a.encodeFlashes(holder.out)
+	if err == nil && len(b) > maxCookieSize && holder.out.Form != nil {
+		log.Printf("Saving flashes: cookie of %d bytes is too large, form values dropped", len(b))
+		holder.out.Form = nil
+		b, err = a.encodeFlashes(holder.out)
+	}
+	if err == nil && len(b) > maxCookieSize {
+		err = fmt.Errorf("cookie of %d bytes is too large, browsers would drop it", len(b))
+	}
+	if err != nil {
+		log.Printf("Saving flashes: %v", err)
+		if holder.read && holder.in != nil {
+			a.deleteCookie(w, &http.Cookie{Name: flashCookieName})
+		}
+		return
+	}
+	a.setCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Value:    string(b),
+		HttpOnly: true,
+	})
+}
+
+// encodeFlashes returns the encrypted value of the flash cookie.
+func (a *App) encodeFlashes(data *flashData) ([]byte, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return a.encrypter.EncryptBase64(b)
+}
@@ -0,0 +1,40 @@
+package app_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+	"github.com/gowww/check"
+)
+
+func TestFlashErrorsForm(t *testing.T) {
+	checker := check.Checker{"email": {check.Required, check.Email}}
+	a := app.New()
+	a.Secret(newKey)
+	a.Post("/signup", func(c *app.Context) {
+		c.FlashErrors(c.Check(checker))
+	})
+	a.Post("/contact", func(c *app.Context) {
+		c.FlashErrors(c.Check(checker), "email")
+	})
+	a.Get("/", func(c *app.Context) {
+		c.JSON(c.FlashedForm())
+	})
+
+	c := apptest.New(t, a)
+	c.PostForm("/signup", url.Values{"email": {"wrong"}, "password": {"s3cret"}, "new_password": {"s3cret"}, "csrf_token": {"t0k3n"}})
+	c.Get("/").JSON(url.Values{"email": {"wrong"}})
+	c.PostForm("/contact", url.Values{"email": {"wrong"}, "name": {"ann"}})
+	c.Get("/").JSON(url.Values{"email": {"wrong"}})
+
+	r := c.PostForm("/signup", url.Values{"email": {"wrong"}, "message": {strings.Repeat("long ", 1000)}})
+	if ck := responseCookie(t, r, "flash"); len(ck.Value) > 4096 {
+		t.Errorf("flash cookie: want at most 4096 bytes, got %d", len(ck.Value))
+	}
+	var form url.Values
+	c.Get("/").Status(http.StatusOK).JSON(form)
+}
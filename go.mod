@@ -8,12 +8,13 @@ require (
 	github.com/gowww/cli v1.0.1
 	github.com/gowww/compress v1.0.0
 	github.com/gowww/crypto v1.0.0
-	github.com/gowww/fatal v1.0.0
 	github.com/gowww/i18n v1.0.0
 	github.com/gowww/log v1.0.0
 	github.com/gowww/router v1.0.0
 	github.com/gowww/secure v1.0.2
 	github.com/gowww/static v1.0.0
 	github.com/gowww/view v1.0.0
+	github.com/vmihailenco/msgpack/v4 v4.3.12
 	golang.org/x/text v0.3.3
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -3,48 +3,206 @@ package app
 import "net/http"
 
 // RouterGroup contains the first path part for a routes group.
+//
+// A group inherits the middlewares of its parents, through any nesting depth.
+// They wrap a route handler in this order: parent group middlewares first, then child group middlewares, then route middlewares.
 type RouterGroup struct {
+	app         *App
+	parent      *RouterGroup
 	path        string
-	middlewares []Middleware
+	middlewares []*GroupMiddleware
+	skipped     []*GroupMiddleware
+	skipCSRF    bool
+	policies    []Policy
+	corsOptions *CORSOptions
+}
+
+// A GroupMiddleware is a middleware used by a group, that subgroups can opt out of (see RouterGroup.Use and RouterGroup.Skip).
+type GroupMiddleware struct {
+	m Middleware
+}
+
+// Group initiates a routing group.
+// All subroutes paths will be prefixed with the group path.
+func (a *App) Group(path string, middlewares ...Middleware) *RouterGroup {
+	return &RouterGroup{app: a, path: path, middlewares: groupMiddlewares(middlewares)}
 }
 
 // Group initiates a routing group.
 // All subroutes paths will be prefixed with the group path.
 func Group(path string, middlewares ...Middleware) *RouterGroup {
-	return &RouterGroup{path, middlewares}
+	return defaultApp.Group(path, middlewares...)
 }
 
 // Group contains the first path part for a routes subgroup.
+// The subgroup inherits the middlewares of rg.
 func (rg *RouterGroup) Group(path string, middlewares ...Middleware) *RouterGroup {
-	return &RouterGroup{rg.path + path, middlewares}
+	return &RouterGroup{app: rg.app, parent: rg, path: rg.path + path, middlewares: groupMiddlewares(middlewares)}
+}
+
+// groupMiddlewares returns middlewares mm used by a group.
+func groupMiddlewares(mm []Middleware) []*GroupMiddleware {
+	gmm := make([]*GroupMiddleware, len(mm))
+	for i, m := range mm {
+		gmm[i] = &GroupMiddleware{m}
+	}
+	return gmm
+}
+
+// Use adds middleware m to the group, after the ones already set, and returns it so subgroups can opt out of it with RouterGroup.Skip.
+// It must be called before making the group routes.
+func (rg *RouterGroup) Use(m Middleware) *GroupMiddleware {
+	gm := &GroupMiddleware{m}
+	rg.middlewares = append(rg.middlewares, gm)
+	return gm
+}
+
+// Skip opts the group (and its subgroups) out of middlewares inherited from parent groups, as returned by RouterGroup.Use.
+func (rg *RouterGroup) Skip(middlewares ...*GroupMiddleware) *RouterGroup {
+	rg.skipped = append(rg.skipped, middlewares...)
+	return rg
+}
+
+// chain returns the inherited and own middlewares of the group, in wrapping order.
+func (rg *RouterGroup) chain() []*GroupMiddleware {
+	var gmm []*GroupMiddleware
+	if rg.parent != nil {
+		for _, gm := range rg.parent.chain() {
+			if !containsGroupMiddleware(rg.skipped, gm) {
+				gmm = append(gmm, gm)
+			}
+		}
+	}
+	return append(gmm, rg.middlewares...)
+}
+
+// containsGroupMiddleware tells if gm is one of gmm.
+func containsGroupMiddleware(gmm []*GroupMiddleware, gm *GroupMiddleware) bool {
+	for _, v := range gmm {
+		if v == gm {
+			return true
+		}
+	}
+	return false
 }
 
 // Route makes a route for method and path.
-func (rg *RouterGroup) Route(method, path string, handler Handler, middlewares ...Middleware) {
-	rt.Handle(method, rg.path+path, wrapHandler(wrapHandler(handler, middlewares...), rg.middlewares...))
+func (rg *RouterGroup) Route(method, path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.route(method, path, handler, middlewares)
+}
+
+// RouteErr makes a route for method and path.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) RouteErr(method, path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.route(method, path, handler, middlewares)
+}
+
+// route makes a route for method and path, with the group path, middlewares and options.
+func (rg *RouterGroup) route(method, path string, handler http.Handler, middlewares []Middleware) *RouteEntry {
+	var mm []Middleware
+	for _, gm := range rg.chain() {
+		mm = append(mm, gm.m)
+	}
+	re := rg.app.handle(method, rg.path+path, handler, append(mm, middlewares...))
+	if rg.skipsCSRF() {
+		re.SkipCSRF()
+	}
+	re.group = rg
+	if o := rg.corsConfig(); o != nil {
+		re.corsOptions = o
+		rg.app.corsRoutes = true
+	}
+	return re
 }
 
 // Get makes a route for GET method.
-func (rg *RouterGroup) Get(path string, handler Handler, middlewares ...Middleware) {
-	rg.Route(http.MethodGet, path, handler, middlewares...)
+func (rg *RouterGroup) Get(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodGet, path, handler, middlewares...)
+}
+
+// GetErr makes a route for GET method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) GetErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodGet, path, handler, middlewares...)
+}
+
+// Head makes a route for HEAD method.
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func (rg *RouterGroup) Head(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodHead, path, handler, middlewares...)
+}
+
+// HeadErr makes a route for HEAD method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic HEAD response, made by the GET route with the body discarded.
+func (rg *RouterGroup) HeadErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodHead, path, handler, middlewares...)
 }
 
 // Post makes a route for POST method.
-func (rg *RouterGroup) Post(path string, handler Handler, middlewares ...Middleware) {
-	rg.Route(http.MethodPost, path, handler, middlewares...)
+func (rg *RouterGroup) Post(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodPost, path, handler, middlewares...)
+}
+
+// PostErr makes a route for POST method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) PostErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodPost, path, handler, middlewares...)
 }
 
 // Put makes a route for PUT method.
-func (rg *RouterGroup) Put(path string, handler Handler, middlewares ...Middleware) {
-	rg.Route(http.MethodPut, path, handler, middlewares...)
+func (rg *RouterGroup) Put(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodPut, path, handler, middlewares...)
+}
+
+// PutErr makes a route for PUT method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) PutErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodPut, path, handler, middlewares...)
 }
 
 // Patch makes a route for PATCH method.
-func (rg *RouterGroup) Patch(path string, handler Handler, middlewares ...Middleware) {
-	rg.Route(http.MethodPatch, path, handler, middlewares...)
+func (rg *RouterGroup) Patch(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodPatch, path, handler, middlewares...)
+}
+
+// PatchErr makes a route for PATCH method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) PatchErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodPatch, path, handler, middlewares...)
 }
 
 // Delete makes a route for DELETE method.
-func (rg *RouterGroup) Delete(path string, handler Handler, middlewares ...Middleware) {
-	rg.Route(http.MethodDelete, path, handler, middlewares...)
+func (rg *RouterGroup) Delete(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodDelete, path, handler, middlewares...)
+}
+
+// DeleteErr makes a route for DELETE method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) DeleteErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodDelete, path, handler, middlewares...)
+}
+
+// Options makes a route for OPTIONS method.
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func (rg *RouterGroup) Options(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(http.MethodOptions, path, handler, middlewares...)
+}
+
+// OptionsErr makes a route for OPTIONS method.
+// The handler returns an error to stop with an error response (see ErrHandler).
+// It's only useful to override the automatic OPTIONS response, giving the allowed methods for the path.
+func (rg *RouterGroup) OptionsErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(http.MethodOptions, path, handler, middlewares...)
+}
+
+// Any makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+func (rg *RouterGroup) Any(path string, handler Handler, middlewares ...Middleware) *RouteEntry {
+	return rg.Route(methodAny, path, handler, middlewares...)
+}
+
+// AnyErr makes a route for all common methods: GET, HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+// The handler returns an error to stop with an error response (see ErrHandler).
+func (rg *RouterGroup) AnyErr(path string, handler ErrHandler, middlewares ...Middleware) *RouteEntry {
+	return rg.RouteErr(methodAny, path, handler, middlewares...)
 }
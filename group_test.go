@@ -0,0 +1,80 @@
+package app_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+// tag returns a middleware appending s to header "X-Chain".
+func tag(s string) app.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", s)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func auth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func TestGroupMiddlewares(t *testing.T) {
+	a := app.New()
+	ok := func(c *app.Context) { c.Text("ok") }
+
+	admin := a.Group("/admin")
+	adminAuth := admin.Use(auth)
+	admin.Use(tag("admin"))
+	{
+		users := admin.Group("/users", tag("users"))
+		{
+			users.Get("/list", ok, tag("route"))
+		}
+		health := admin.Group("/health").Skip(adminAuth)
+		{
+			health.Get("/check", ok)
+		}
+	}
+
+	c := apptest.New(t, a)
+	c.Get("/admin/users/list").Status(http.StatusUnauthorized)
+	c.Get("/admin/health/check").Status(http.StatusOK)
+
+	c.SetHeader("Authorization", "Bearer token")
+	res := c.Get("/admin/users/list").Status(http.StatusOK)
+	if chain := res.Recorder.Result().Header["X-Chain"]; len(chain) != 3 || chain[0] != "admin" || chain[1] != "users" || chain[2] != "route" {
+		t.Errorf("middlewares order: want [admin users route], got %v", chain)
+	}
+}
+
+func TestGroupSkipInstance(t *testing.T) {
+	a := app.New()
+	ok := func(c *app.Context) { c.Text("ok") }
+
+	admin := a.Group("/admin")
+	adminAuth := admin.Use(auth)
+	{
+		staff := admin.Group("/staff")
+		staffAuth := staff.Use(auth) // Same function, but another middleware for Skip.
+		{
+			staff.Group("/public").Skip(adminAuth).Get("/check", ok)
+			staff.Group("/open").Skip(adminAuth, staffAuth).Get("/check", ok)
+		}
+		admin.Group("/public").Skip(adminAuth).Get("/check", ok)
+	}
+
+	c := apptest.New(t, a)
+	c.Get("/admin/staff/public/check").Status(http.StatusUnauthorized)
+	c.Get("/admin/staff/open/check").Status(http.StatusOK).Body("ok")
+	c.Get("/admin/public/check").Status(http.StatusOK).Body("ok")
+}
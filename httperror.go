@@ -0,0 +1,238 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gowww/check"
+)
+
+// correlationIDHeader is the header used to receive and send the correlation ID of a request.
+const correlationIDHeader = "X-Correlation-ID"
+
+// correlationIDMaxLen is the maximum length of a correlation ID received from the client.
+const correlationIDMaxLen = 128
+
+// An HTTPError is an error with an HTTP status, responded as an RFC 7807 "problem details" object.
+// A handler can panic with it (or pass it to Context.Problem) to stop and respond with the error.
+//
+// When the client prefers HTML and an error view is set (see App.ErrorView), the view is rendered instead of the "application/problem+json" object.
+type HTTPError struct {
+	Status     int                    // Status is the HTTP status code, "500 Internal Server Error" if not set.
+	Type       string                 // Type is a URI reference identifying the problem type, "about:blank" if not set.
+	Title      string                 // Title is a short summary of the problem type, the status text if not set.
+	Detail     string                 // Detail is an explanation specific to this occurrence of the problem.
+	Instance   string                 // Instance is a URI reference identifying this occurrence of the problem.
+	Errors     check.Errors           // Errors are the checking errors, sent translated in the "errors" member.
+	Extensions map[string]interface{} // Extensions are additional members of the problem object.
+	Err        error                  // Err is the underlying error, never sent to the client.
+}
+
+// NewHTTPError returns an HTTPError with status and detail.
+func NewHTTPError(status int, detail string) *HTTPError {
+	return &HTTPError{Status: status, Detail: detail}
+}
+
+func (e *HTTPError) Error() string {
+	s := fmt.Sprintf("%d %s", e.status(), e.title())
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) status() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+func (e *HTTPError) title() string {
+	if e.Title == "" {
+		return http.StatusText(e.status())
+	}
+	return e.Title
+}
+
+// problem returns the problem details object, with the checking errors translated for the client.
+func (e *HTTPError) problem(c *Context) map[string]interface{} {
+	p := make(map[string]interface{}, len(e.Extensions)+7)
+	for k, v := range e.Extensions {
+		p[k] = v
+	}
+	p["type"] = e.Type
+	if e.Type == "" {
+		p["type"] = "about:blank"
+	}
+	p["title"] = e.title()
+	p["status"] = e.status()
+	if e.Detail != "" {
+		p["detail"] = e.Detail
+	}
+	if e.Instance != "" {
+		p["instance"] = e.Instance
+	}
+	if len(e.Errors) > 0 {
+		p["errors"] = c.TErrors(e.Errors)
+	}
+	p["correlation_id"] = c.CorrelationID()
+	return p
+}
+
+// An errorMapping associates an error to a status code.
+type errorMapping struct {
+	err    error
+	status int
+}
+
+// MapError sets the status code responded when an ErrHandler returns an error matching err (with errors.Is), like sql.ErrNoRows to "404 Not Found".
+// Mappings are tried in the order they are set.
+func (a *App) MapError(err error, status int) {
+	a.errorMappings = append(a.errorMappings, errorMapping{err, status})
+}
+
+// MapError sets the status code responded when an ErrHandler returns an error matching err (with errors.Is), like sql.ErrNoRows to "404 Not Found".
+// Mappings are tried in the order they are set.
+func MapError(err error, status int) {
+	defaultApp.MapError(err, status)
+}
+
+// httpError returns err as an HTTPError: as is if it's one, or wrapped with its mapped status.
+func (a *App) httpError(err error) *HTTPError {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	for _, m := range a.errorMappings {
+		if errors.Is(err, m.err) {
+			return &HTTPError{Status: m.status, Err: err}
+		}
+	}
+	return &HTTPError{Status: http.StatusInternalServerError, Err: err}
+}
+
+// ErrorView sets the view rendered for an HTTPError when the client prefers HTML.
+// The view receives the error as .error and the translated checking errors as .errors, with the .correlationID.
+func (a *App) ErrorView(name string) {
+	if a.errorView != "" {
+		panic("app: error view set multiple times")
+	}
+	a.errorView = name
+}
+
+// ErrorView sets the view rendered for an HTTPError when the client prefers HTML.
+// The view receives the error as .error and the translated checking errors as .errors, with the .correlationID.
+func ErrorView(name string) {
+	defaultApp.ErrorView(name)
+}
+
+// Problem responds with err as an RFC 7807 "application/problem+json" object, or with the error view if the client prefers HTML.
+func (c *Context) Problem(err *HTTPError) {
+	c.Res.Header().Set(correlationIDHeader, c.CorrelationID())
+	if view := c.app().errorView; view != "" && acceptsHTML(c.Req) {
+		c.Status(err.status())
+		c.View(view, ViewData{"error": err, "errors": err.Errors, "correlationID": c.CorrelationID()})
+		return
+	}
+	c.Res.Header().Set("Content-Type", "application/problem+json")
+	c.Status(err.status())
+	if err := json.NewEncoder(c.Res).Encode(err.problem(c)); err != nil {
+		c.Panic(err)
+	}
+}
+
+// CorrelationID returns the ID identifying the request in logs and error responses.
+// It's taken from the "X-Correlation-ID" request header if valid, or generated.
+func (c *Context) CorrelationID() string {
+	if id, ok := c.Get(contextKeyCorrelationID).(string); ok {
+		return id
+	}
+	id := correlationID(c.Req)
+	c.Set(contextKeyCorrelationID, id)
+	return id
+}
+
+// correlationID returns the valid correlation ID sent by the client, or a new one.
+func correlationID(r *http.Request) string {
+	if id := r.Header.Get(correlationIDHeader); id != "" && len(id) <= correlationIDMaxLen {
+		valid := true
+		for i := 0; i < len(id) && valid; i++ {
+			valid = id[i] > ' ' && id[i] < 0x7f
+		}
+		if valid {
+			return id
+		}
+	}
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// acceptsHTML tells if the client prefers HTML over JSON.
+// HTML must be explicitly accepted, not only by a "*/*" range.
+func acceptsHTML(r *http.Request) bool {
+	rr := parseAccept(r.Header.Get("Accept"))
+	html, ok := mediaRange(rr, "text/html")
+	if !ok || html.q <= 0 || html.specificity() == 0 {
+		return false
+	}
+	for _, mt := range []string{"application/json", "application/problem+json"} {
+		if ar, ok := mediaRange(rr, mt); ok && (ar.q > html.q || ar.q == html.q && ar.specificity() > html.specificity()) {
+			return false
+		}
+	}
+	return true
+}
+
+// serveError responds to err with Context.Problem, or with the error handler if set and err is a server error.
+// In development, a recovered panic is responded with the developer error page if the client prefers HTML.
+// An error that is not an HTTPError (from a panic, with its stack trace) is responded as "500 Internal Server Error".
+// A client error (an HTTPError with a 4xx status) never goes to the error handler, which only handles the internal errors.
+// The error and the stack are available in the handler through Context.Error and Context.ErrorStack.
+func (a *App) serveError(w http.ResponseWriter, r *http.Request, err error, stack []byte) {
+	he, ok := err.(*HTTPError)
+	if !ok {
+		he = &HTTPError{Status: http.StatusInternalServerError, Err: err}
+	}
+	if stack != nil {
+		log.Printf("Serving %s: %v\n%s", r.RemoteAddr, err, stack)
+	} else if he.status() >= http.StatusInternalServerError {
+		log.Printf("Serving %s: %v", r.RemoteAddr, err)
+	}
+	ctx := context.WithValue(r.Context(), contextKeyError, err)
+	if stack != nil {
+		ctx = context.WithValue(ctx, contextKeyErrorStack, stack)
+	}
+	r = r.WithContext(ctx)
+	if a.errorHandler != nil && he.status() >= http.StatusInternalServerError {
+		a.errorHandler.ServeHTTP(w, r)
+		return
+	}
+	if !production && stack != nil && acceptsHTML(r) {
+		serveDevError(w, r, err, stack)
+		return
+	}
+	(&Context{Res: w, Req: r}).Problem(he)
+}
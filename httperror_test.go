@@ -0,0 +1,192 @@
+package app_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+	"github.com/gowww/check"
+)
+
+func TestHTTPError(t *testing.T) {
+	a := app.New()
+	a.Get("/users/:id", func(c *app.Context) {
+		panic(&app.HTTPError{
+			Status:     http.StatusNotFound,
+			Detail:     "No user " + c.PathValue("id"),
+			Extensions: map[string]interface{}{"id": c.PathValue("id")},
+		})
+	})
+	a.Post("/users", func(c *app.Context) {
+		errs := make(check.Errors)
+		errs.Add("email", &check.Error{Error: check.ErrRequired})
+		panic(&app.HTTPError{Status: http.StatusUnprocessableEntity, Errors: errs})
+	})
+	a.Get("/crash", func(c *app.Context) {
+		panic("crash")
+	})
+
+	c := apptest.New(t, a)
+	c.SetHeader("X-Correlation-ID", "abc")
+	c.Get("/users/42").
+		Status(http.StatusNotFound).
+		Header("Content-Type", "application/problem+json").
+		Header("X-Correlation-ID", "abc").
+		JSON(map[string]interface{}{
+			"type":           "about:blank",
+			"title":          "Not Found",
+			"status":         404,
+			"detail":         "No user 42",
+			"id":             "42",
+			"correlation_id": "abc",
+		})
+	c.PostForm("/users", nil).
+		Status(http.StatusUnprocessableEntity).
+		CheckError("email", "A value is required.")
+	c.Get("/crash").
+		Status(http.StatusInternalServerError).
+		Header("Content-Type", "application/problem+json")
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	errDown := errors.New("down")
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		panic(&app.HTTPError{Status: http.StatusServiceUnavailable, Err: errDown})
+	})
+	a.Get("/missing", func(c *app.Context) {
+		panic(&app.HTTPError{Status: http.StatusNotFound})
+	})
+	a.Error(func(c *app.Context) {
+		var err *app.HTTPError
+		if !errors.As(c.Error(), &err) || !errors.Is(err, errDown) {
+			t.Errorf("Context.Error: want *HTTPError wrapping %v, got %#v", errDown, c.Error())
+		}
+		c.Status(err.Status)
+		c.Text(c.CorrelationID())
+	})
+
+	c := apptest.New(t, a)
+	res := c.Get("/").Status(http.StatusServiceUnavailable)
+	if res.Recorder.Body.Len() != 32 {
+		t.Errorf("correlation ID: want 32 hex characters, got %q", res.Recorder.Body)
+	}
+	c.Get("/missing").Status(http.StatusNotFound).Header("Content-Type", "application/problem+json") // Client errors don't go to the error handler.
+}
+
+func TestBadRequest(t *testing.T) {
+	a := app.New()
+	a.Post("/users", func(c *app.Context) {
+		if c.BadRequest(check.Checker{"email": {check.Required, check.Email}}, "") {
+			return
+		}
+		c.Text("created")
+	})
+	a.Error(func(c *app.Context) {
+		t.Errorf("error handler: want not used for a bad request, got %v", c.Error())
+	})
+
+	apptest.New(t, a).PostForm("/users", nil).
+		Status(http.StatusBadRequest).
+		Header("Content-Type", "application/json").
+		Body(`{"errors":{"email":["required"]}}` + "\n")
+}
+
+func TestErrHandler(t *testing.T) {
+	errNoRows := errors.New("no rows")
+	errDisk := errors.New("disk full")
+	a := app.New()
+	a.MapError(errNoRows, http.StatusNotFound)
+	a.GetErr("/users/:id", func(c *app.Context) error {
+		return fmt.Errorf("user %s: %w", c.PathValue("id"), errNoRows)
+	})
+	a.Group("/api").GetErr("/forbidden", func(c *app.Context) error {
+		return app.NewHTTPError(http.StatusForbidden, "Nope.")
+	})
+	a.PostErr("/upload", func(c *app.Context) error {
+		return errDisk
+	})
+	a.GetErr("/ok", func(c *app.Context) error {
+		c.Text("ok")
+		return nil
+	})
+	a.GetErr("/partial", func(c *app.Context) error {
+		c.Text("partial")
+		return errDisk
+	})
+
+	c := apptest.New(t, a)
+	c.Get("/users/42").Status(http.StatusNotFound).Header("Content-Type", "application/problem+json")
+	c.Get("/api/forbidden").Status(http.StatusForbidden).BodyContains(`"detail":"Nope."`)
+	c.PostForm("/upload", nil).Status(http.StatusInternalServerError)
+	c.Get("/ok").Status(http.StatusOK).Body("ok")
+	c.Get("/partial").Status(http.StatusOK).Body("partial") // The response has started: the error is only logged.
+
+	a.Error(func(c *app.Context) {
+		if !errors.Is(c.Error(), errDisk) {
+			t.Errorf("Context.Error: want %v, got %v", errDisk, c.Error())
+		}
+		c.Status(http.StatusInsufficientStorage)
+	})
+	c.PostForm("/upload", nil).Status(http.StatusInsufficientStorage)
+}
+
+type quotaError struct{ used int }
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota exceeded: %d", e.used) }
+
+func TestErrorHandlerPanic(t *testing.T) {
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		panic(&quotaError{used: 42})
+	})
+	a.Error(func(c *app.Context) {
+		var err *quotaError
+		if !errors.As(c.Error(), &err) || err.used != 42 {
+			t.Errorf("Context.Error: want the panicked *quotaError, got %#v", c.Error())
+		}
+		c.Status(http.StatusInternalServerError)
+		c.Text("handled")
+	})
+
+	apptest.New(t, a).Get("/").Status(http.StatusInternalServerError).Body("handled")
+}
+
+func TestContextError(t *testing.T) {
+	errStorage := errors.New("storage")
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		c.Text("ok")
+	})
+	a.Get("/panic", func(c *app.Context) {
+		c.Panic(errStorage)
+	})
+	a.Get("/value", func(c *app.Context) {
+		panic("value")
+	})
+	a.Error(func(c *app.Context) {
+		c.Status(http.StatusInternalServerError)
+		if !bytes.Contains(c.ErrorStack(), []byte("panic")) {
+			t.Errorf("Context.ErrorStack: want a stack trace, got %q", c.ErrorStack())
+		}
+		if errors.Is(c.Error(), errStorage) {
+			c.Text("storage")
+			return
+		}
+		c.Text(c.Error().Error())
+	})
+	a.NotFound(func(c *app.Context) {
+		if err := c.Error(); err != nil {
+			t.Errorf("Context.Error: want nil, got %v", err)
+		}
+	})
+
+	c := apptest.New(t, a)
+	c.Get("/panic").Status(http.StatusInternalServerError).Body("storage")
+	c.Get("/value").Status(http.StatusInternalServerError).Body("value")
+	c.Get("/unknown")
+}
@@ -5,22 +5,22 @@ import (
 	"golang.org/x/text/language"
 )
 
-var confI18n struct {
-	Locales  i18n.Locales
-	Fallback language.Tag
-	Parsers  []i18n.Parser
-}
-
 // Localize sets app locales with fallback and client locale parsers.
 // Order is mandatory and defaults are: ParseCookie, ParseFormValue, ParseAcceptLanguage.
-func Localize(locs i18n.Locales, fallback language.Tag, parsers ...i18n.Parser) {
-	if len(confI18n.Parsers) > 0 {
+func (a *App) Localize(locs i18n.Locales, fallback language.Tag, parsers ...i18n.Parser) {
+	if len(a.confI18n.Parsers) > 0 {
 		panic("app: locales set multiple times")
 	}
 	if len(parsers) == 0 {
 		parsers = []i18n.Parser{i18n.ParseCookie, i18n.ParseFormValue, i18n.ParseAcceptLanguage}
 	}
-	confI18n.Fallback = fallback
-	confI18n.Locales = locs
-	confI18n.Parsers = parsers
+	a.confI18n.Fallback = fallback
+	a.confI18n.Locales = locs
+	a.confI18n.Parsers = parsers
+}
+
+// Localize sets app locales with fallback and client locale parsers.
+// Order is mandatory and defaults are: ParseCookie, ParseFormValue, ParseAcceptLanguage.
+func Localize(locs i18n.Locales, fallback language.Tag, parsers ...i18n.Parser) {
+	defaultApp.Localize(locs, fallback, parsers...)
 }
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gowww/crypto"
+)
+
+// A Keyring is an encrypter for key rotation: it encrypts with a primary key and decrypts with the primary key or older ones.
+// It's the app encrypter (see App.Secret).
+type Keyring struct {
+	primary crypto.Encrypter
+	old     []crypto.Encrypter
+}
+
+// NewKeyring returns a keyring encrypting with primary key, and decrypting with primary or old keys.
+// Keys must be 32 bytes long.
+func NewKeyring(primary string, old ...string) (*Keyring, error) {
+	k := new(Keyring)
+	for i, key := range append([]string{primary}, old...) {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secret key %d is %d bytes long, want 32", i+1, len(key))
+		}
+		e, err := crypto.NewEncrypter([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			k.primary = e
+		} else {
+			k.old = append(k.old, e)
+		}
+	}
+	return k, nil
+}
+
+// Encrypt implements crypto.Encrypter with the primary key.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return k.primary.Encrypt(plaintext)
+}
+
+// EncryptBase64 implements crypto.Encrypter with the primary key.
+func (k *Keyring) EncryptBase64(plaintext []byte) ([]byte, error) {
+	return k.primary.EncryptBase64(plaintext)
+}
+
+// Decrypt implements crypto.Encrypter with the primary key, or the old ones.
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	b, _, err := k.decrypt(ciphertext, crypto.Encrypter.Decrypt)
+	return b, err
+}
+
+// DecryptBase64 implements crypto.Encrypter with the primary key, or the old ones.
+func (k *Keyring) DecryptBase64(ciphertext []byte) ([]byte, error) {
+	b, _, err := k.decrypt(ciphertext, crypto.Encrypter.DecryptBase64)
+	return b, err
+}
+
+// decryptBase64 works like DecryptBase64 but also tells if an old key has been used, so the value can be encrypted again with the primary key.
+func (k *Keyring) decryptBase64(ciphertext []byte) (plaintext []byte, stale bool, err error) {
+	return k.decrypt(ciphertext, crypto.Encrypter.DecryptBase64)
+}
+
+// decrypt tries decrypt with each key, starting with the primary one.
+func (k *Keyring) decrypt(ciphertext []byte, decrypt func(crypto.Encrypter, []byte) ([]byte, error)) ([]byte, bool, error) {
+	b, err := decrypt(k.primary, ciphertext)
+	if err == nil {
+		return b, false, nil
+	}
+	for _, e := range k.old {
+		if b, oldErr := decrypt(e, ciphertext); oldErr == nil {
+			return b, true, nil
+		}
+	}
+	return nil, false, err
+}
+
+// sign returns the HMAC-SHA256 of msg with the primary key.
+func (k *Keyring) sign(msg []byte) []byte {
+	sig, err := k.primary.HashHS256(msg)
+	if err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+	return sig
+}
+
+// verify tells if sig is the HMAC-SHA256 of msg with one of the keys, and if it's an old one.
+func (k *Keyring) verify(msg, sig []byte) (ok, stale bool) {
+	for i, e := range append([]crypto.Encrypter{k.primary}, k.old...) {
+		if want, err := e.HashHS256(msg); err == nil && hmac.Equal(sig, want) {
+			return true, i > 0
+		}
+	}
+	return false, false
+}
+
+// HashHS256 implements crypto.Encrypter with the primary key.
+func (k *Keyring) HashHS256(plaintext []byte) ([]byte, error) {
+	return k.primary.HashHS256(plaintext)
+}
+
+// decryptBase64 decrypts a base64 value with encrypter and tells if it has been decrypted with an old key of a keyring.
+func decryptBase64(encrypter crypto.Encrypter, v []byte) ([]byte, bool, error) {
+	if k, ok := encrypter.(*Keyring); ok {
+		return k.decryptBase64(v)
+	}
+	b, err := encrypter.DecryptBase64(v)
+	return b, false, err
+}
+
+// Secret sets the secret keys used for encryption: the primary key, and old keys only used for decryption.
+// Keys must be 32 bytes long.
+//
+// To rotate the key, set a new primary key and keep the previous one as an old key for a while: sessions and the cookies read with Context.RefreshCookie or Context.CookieJSON are re-issued with the new key when they are read.
+// Context.Cookie and Context.CookieWith don't re-issue cookies, as they don't know the attributes they have been set with.
+func (a *App) Secret(key string, old ...string) {
+	if a.encrypter != nil {
+		panic("app: secret key set multiple times")
+	}
+	var err error
+	a.encrypter, err = NewKeyring(key, old...)
+	if err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+}
+
+// Secret sets the secret keys used for encryption: the primary key, and old keys only used for decryption.
+// Keys must be 32 bytes long.
+//
+// To rotate the key, set a new primary key and keep the previous one as an old key for a while: sessions and the cookies read with Context.RefreshCookie or Context.CookieJSON are re-issued with the new key when they are read.
+// Context.Cookie and Context.CookieWith don't re-issue cookies, as they don't know the attributes they have been set with.
+func Secret(key string, old ...string) {
+	defaultApp.Secret(key, old...)
+}
+
+// SecretEnv sets the secret keys from the environment variable name.
+// Keys are separated by white space, the primary key first (see Secret).
+func (a *App) SecretEnv(name string) {
+	v := os.Getenv(name)
+	if v == "" {
+		panic(fmt.Errorf("app: no secret key in environment variable %s", name))
+	}
+	a.secretKeys(v)
+}
+
+// SecretEnv sets the secret keys from the environment variable name.
+// Keys are separated by white space, the primary key first (see Secret).
+func SecretEnv(name string) {
+	defaultApp.SecretEnv(name)
+}
+
+// SecretFile sets the secret keys from the file at path.
+// Keys are separated by white space (like one per line), the primary key first (see Secret).
+func (a *App) SecretFile(path string) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+	a.secretKeys(string(b))
+}
+
+// SecretFile sets the secret keys from the file at path.
+// Keys are separated by white space (like one per line), the primary key first (see Secret).
+func SecretFile(path string) {
+	defaultApp.SecretFile(path)
+}
+
+// secretKeys sets the secret keys from white space separated keys.
+func (a *App) secretKeys(s string) {
+	keys := strings.Fields(s)
+	if len(keys) == 0 {
+		panic("app: no secret key")
+	}
+	a.Secret(keys[0], keys[1:]...)
+}
@@ -0,0 +1,103 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+const (
+	oldKey = "ab7d8ee1c25e4f6a8d8b4a9c27f1f1e3"
+	newKey = "3rpJFaa8Jtx2TFzWV2n0ymFbcuBBP7y6"
+)
+
+func keyringApp(key string, old ...string) *app.App {
+	a := app.New()
+	a.Secret(key, old...)
+	a.Get("/", func(c *app.Context) {
+		c.Text(c.RefreshCookie(&http.Cookie{Name: "theme", Path: "/", MaxAge: 3600}, app.CookieDefault) + " " + c.Session().String("user"))
+	})
+	a.Post("/", func(c *app.Context) {
+		c.SetCookie(&http.Cookie{Name: "theme", Value: "dark", Path: "/", MaxAge: 3600})
+		c.Session().Set("user", "ann")
+	})
+	return a
+}
+
+func TestSecretRotation(t *testing.T) {
+	old := apptest.New(t, keyringApp(oldKey))
+	old.PostForm("/", url.Values{})
+
+	rotated := apptest.New(t, keyringApp(newKey, oldKey))
+	for _, ck := range old.Cookies() {
+		rotated.SetCookie(ck)
+	}
+	r := rotated.Get("/").Body("dark ann")
+	if ck := responseCookie(t, r, "theme"); ck.Path != "/" || ck.MaxAge != 3600 || !ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode {
+		t.Errorf("re-issued cookie: want path /, max age 3600, HttpOnly and SameSite=Lax, got %v", ck)
+	}
+	if ck := responseCookie(t, r, "session"); ck.Path != "/" || ck.Expires.IsZero() || !ck.HttpOnly {
+		t.Errorf("re-issued session cookie: want path /, expiry and HttpOnly, got %v", ck)
+	}
+
+	fresh := apptest.New(t, keyringApp(newKey))
+	for _, ck := range rotated.Cookies() {
+		fresh.SetCookie(ck)
+	}
+	fresh.Get("/").Body("dark ann") // Cookies have been re-issued with the new key.
+
+	unknown := apptest.New(t, keyringApp(newKey))
+	for _, ck := range old.Cookies() {
+		unknown.SetCookie(ck)
+	}
+	unknown.Get("/").Body(" ")
+}
+
+func TestSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(path, []byte(newKey+"\n"+oldKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := app.New()
+	a.SecretFile(path)
+	testKeyring(t, a)
+}
+
+func TestSecretEnv(t *testing.T) {
+	os.Setenv("APP_TEST_SECRET", newKey+" "+oldKey)
+	defer os.Unsetenv("APP_TEST_SECRET")
+	a := app.New()
+	a.SecretEnv("APP_TEST_SECRET")
+	testKeyring(t, a)
+}
+
+// testKeyring checks that the app encrypts with newKey and decrypts with oldKey.
+func testKeyring(t *testing.T, a *app.App) {
+	primary, err := app.NewKeyring(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := app.NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := a.Encrypter().Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = primary.Decrypt(b); err != nil {
+		t.Errorf("encryption: want primary key: %v", err)
+	}
+	if b, err = old.Encrypt([]byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = a.Encrypter().Decrypt(b); err != nil || string(b) != "secret" {
+		t.Errorf("decryption with old key: want %q, got %q (%v)", "secret", b, err)
+	}
+}
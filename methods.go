@@ -0,0 +1,198 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gowww/router"
+)
+
+// methodAny is the route method used by Any.
+const methodAny = "*"
+
+// anyMethods are the methods handled by an Any route.
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// probeHandler returns the handler marking route re as matched when probing.
+func probeHandler(re *RouteEntry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if pw, ok := w.(*probeWriter); ok {
+			pw.route = re
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// probeWriter keeps the status written and the route matched when probing.
+type probeWriter struct {
+	header http.Header
+	status int
+	route  *RouteEntry
+}
+
+func (pw *probeWriter) Header() http.Header {
+	if pw.header == nil {
+		pw.header = make(http.Header)
+	}
+	return pw.header
+}
+
+func (pw *probeWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (pw *probeWriter) WriteHeader(status int) {
+	pw.status = status
+}
+
+// headWriter discards the body of a response to a HEAD request.
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// allowedMethods returns the sorted methods having a route matching the request path.
+// HEAD is allowed with GET and OPTIONS is always allowed for an existing path.
+func (a *App) allowedMethods(r *http.Request) (allowed []string) {
+	for method := range a.probes {
+		if a.matchRoute(r, method) != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	hasGet, hasHead, hasOptions := false, false, false
+	for _, method := range allowed {
+		switch method {
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodOptions:
+			hasOptions = true
+		}
+	}
+	if hasGet && !hasHead {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !hasOptions {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// routeMatches keeps the routes matched by the probes for a request, by method, so each path is probed only once.
+type routeMatches struct {
+	routes map[string]*RouteEntry
+}
+
+// matchRoute returns the route matching the request path for method, or nil.
+// The match is kept for the request.
+func (a *App) matchRoute(r *http.Request, method string) *RouteEntry {
+	rm, _ := r.Context().Value(contextKeyRouteMatches).(*routeMatches)
+	if rm != nil {
+		if re, ok := rm.routes[method]; ok {
+			return re
+		}
+	}
+	re := a.probeRoute(r, method)
+	if rm != nil {
+		if rm.routes == nil {
+			rm.routes = make(map[string]*RouteEntry)
+		}
+		rm.routes[method] = re
+	}
+	return re
+}
+
+// probeRoute returns the route matching the request path for method, or nil.
+func (a *App) probeRoute(r *http.Request, method string) *RouteEntry {
+	rt := a.probes[method]
+	if rt == nil {
+		return nil
+	}
+	pw := new(probeWriter)
+	rt.ServeHTTP(pw, requestWithMethod(r, method))
+	if pw.status != http.StatusOK {
+		return nil
+	}
+	return pw.route
+}
+
+// requestWithMethod returns a copy of r with method.
+// The URL is copied too, as the router rewrites its path in place (when redirecting a trailing slash, for example).
+func requestWithMethod(r *http.Request, method string) *http.Request {
+	rc := r.WithContext(r.Context())
+	rc.Method = method
+	u := *r.URL
+	rc.URL = &u
+	return rc
+}
+
+// probe registers route re for method in the probing routers.
+func (a *App) probe(method string, re *RouteEntry) {
+	rt := a.probes[method]
+	if rt == nil {
+		rt = router.New()
+		a.probes[method] = rt
+	}
+	rt.Handle(method, re.path, probeHandler(re))
+}
+
+// serveUnmatched responds to a request having no route for its method.
+// If the path exists for other methods, a HEAD request is served by the GET route, an OPTIONS request gets the allowed methods and others get the "method not allowed" handler.
+// Otherwise, the "not found" handler is used.
+func (a *App) serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	allowed := a.allowedMethods(r)
+	if len(allowed) == 0 {
+		a.serveNotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodHead && containsString(allowed, http.MethodGet) {
+		a.rt.ServeHTTP(&headWriter{w}, requestWithMethod(r, http.MethodGet))
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if a.methodNotAllowedHandler != nil {
+		a.methodNotAllowedHandler.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// serveNotFound responds with the "not found" handler.
+func (a *App) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	if a.notFoundHandler != nil {
+		a.notFoundHandler.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// containsString tells if s is one of ss.
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
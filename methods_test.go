@@ -0,0 +1,35 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func TestMethods(t *testing.T) {
+	a := app.New()
+	a.Get("/users", func(c *app.Context) { c.Text("users") })
+	a.Post("/users", func(c *app.Context) { c.Status(http.StatusCreated) })
+	a.Any("/any", func(c *app.Context) { c.Text(c.Req.Method) })
+	a.Group("/api").Options("/custom", func(c *app.Context) { c.Text("custom") })
+	a.MethodNotAllowed(func(c *app.Context) {
+		c.Status(http.StatusMethodNotAllowed)
+		c.Text("nope")
+	})
+
+	c := apptest.New(t, a)
+	c.Head("/users").Status(http.StatusOK).Body("")
+	c.Delete("/users").
+		Status(http.StatusMethodNotAllowed).
+		Header("Allow", "GET, HEAD, OPTIONS, POST").
+		Body("nope")
+	c.Do(httptest.NewRequest(http.MethodOptions, "/users", nil)).
+		Status(http.StatusNoContent).
+		Header("Allow", "GET, HEAD, OPTIONS, POST")
+	c.Do(httptest.NewRequest(http.MethodOptions, "/api/custom", nil)).Body("custom")
+	c.Delete("/any").Body("DELETE")
+	c.Delete("/unknown").Status(http.StatusNotFound)
+}
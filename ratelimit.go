@@ -0,0 +1,229 @@
+package app
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitPruneInterval is the minimum duration between two removals of the expired states from a memory store.
+const rateLimitPruneInterval = time.Minute
+
+// A RateLimitAlgorithm is the way requests are counted by a rate limit.
+type RateLimitAlgorithm int
+
+// Rate limit algorithms.
+const (
+	TokenBucket   RateLimitAlgorithm = iota // TokenBucket allows bursts of Limit requests, refilled continuously over the window.
+	SlidingWindow                           // SlidingWindow allows Limit requests over any window, estimated from the previous and current fixed windows.
+)
+
+// A RateLimitKey returns the key requests are counted by.
+type RateLimitKey func(c *Context) string
+
+// RateLimitByIP counts requests by client IP address.
+// Behind a proxy, use your own key from the forwarded address.
+func RateLimitByIP(c *Context) string {
+	if host, _, err := net.SplitHostPort(c.Req.RemoteAddr); err == nil {
+		return host
+	}
+	return c.Req.RemoteAddr
+}
+
+// RateLimitByUser counts requests by authenticated user (see Context.User), or by client IP address for anonymous requests.
+func RateLimitByUser(c *Context) string {
+	if p := c.User(); p != nil {
+		return "user:" + p.ID
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByRoute counts requests by route, for all clients.
+func RateLimitByRoute(c *Context) string {
+	if re := c.app().matchRoute(c.Req, c.Req.Method); re != nil {
+		return re.method + " " + re.path
+	}
+	return c.Req.Method + " " + c.Req.URL.Path
+}
+
+// RateLimitState is the state of a rate limit key, as kept by a RateLimitStore.
+type RateLimitState struct {
+	Count    float64   // Count is the tokens left (token bucket) or the requests of the current window (sliding window).
+	Previous float64   // Previous is the requests of the previous window (sliding window).
+	Time     time.Time // Time is the last refill (token bucket) or the current window start (sliding window).
+}
+
+// A RateLimitStore keeps the rate limit states.
+type RateLimitStore interface {
+	// Update atomically updates the state of key with f, given the current state (zero if unknown).
+	// The state can be forgotten after ttl.
+	Update(key string, ttl time.Duration, f func(s *RateLimitState)) error
+}
+
+// MemoryRateLimitStore keeps the rate limit states in memory.
+// They are not shared between multiple instances of the app.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	states    map[string]*memoryRateLimitState
+	lastPrune time.Time
+}
+
+// memoryRateLimitState is a state kept by a MemoryRateLimitStore, with its expiry.
+type memoryRateLimitState struct {
+	RateLimitState
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns a new in-memory rate limit store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{states: make(map[string]*memoryRateLimitState), lastPrune: time.Now()}
+}
+
+// Update implements RateLimitStore.
+func (st *MemoryRateLimitStore) Update(key string, ttl time.Duration, f func(s *RateLimitState)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := time.Now()
+	s, ok := st.states[key]
+	if !ok || now.After(s.expires) {
+		s = new(memoryRateLimitState)
+		st.states[key] = s
+	}
+	f(&s.RateLimitState)
+	s.expires = now.Add(ttl)
+	if now.Sub(st.lastPrune) > rateLimitPruneInterval {
+		for k, s := range st.states {
+			if now.After(s.expires) {
+				delete(st.states, k)
+			}
+		}
+		st.lastPrune = now
+	}
+	return nil
+}
+
+// RateLimitOptions are the options of a rate limit.
+type RateLimitOptions struct {
+	Limit     int                // Limit is the number of requests allowed by window.
+	Window    time.Duration      // Window is the duration of the limit.
+	Algorithm RateLimitAlgorithm // Algorithm counts requests (TokenBucket by default).
+	Key       RateLimitKey       // Key returns the key requests are counted by (RateLimitByIP by default).
+	Store     RateLimitStore     // Store keeps the counts (a new MemoryRateLimitStore by default).
+	Name      string             // Name prefixes the keys, to share a store between multiple rate limits.
+	Handler   Handler            // Handler responds when the limit is reached (a "429 Too Many Requests" problem by default).
+}
+
+// A rateLimitResult is the outcome of a request counting.
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration // reset is the time until the limit is fully available again.
+	retryAfter time.Duration // retryAfter is the time until a request is allowed again.
+}
+
+// RateLimit returns a middleware limiting the number of requests, to be used for a route or a group (like a login route against credential stuffing).
+// Responses have the "RateLimit-Limit", "RateLimit-Remaining" and "RateLimit-Reset" headers, and a refused request gets a "Retry-After" header.
+// If the store fails, the request is allowed and the error is logged.
+func RateLimit(o *RateLimitOptions) Middleware {
+	if o == nil || o.Limit <= 0 || o.Window <= 0 {
+		panic("app: rate limit needs a positive limit and window")
+	}
+	opts := *o
+	if opts.Key == nil {
+		opts.Key = RateLimitByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+	return func(h http.Handler) http.Handler {
+		return Handler(func(c *Context) {
+			key := opts.Name + ":" + opts.Key(c)
+			var res rateLimitResult
+			now := time.Now()
+			err := opts.Store.Update(key, 2*opts.Window, func(s *RateLimitState) {
+				if opts.Algorithm == SlidingWindow {
+					res = opts.slidingWindow(s, now)
+				} else {
+					res = opts.tokenBucket(s, now)
+				}
+			})
+			if err != nil {
+				log.Printf("Rate limiting %s: %v", c.Req.RemoteAddr, err)
+				h.ServeHTTP(c.Res, c.Req)
+				return
+			}
+			header := c.Res.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(opts.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(res.reset))
+			if res.allowed {
+				h.ServeHTTP(c.Res, c.Req)
+				return
+			}
+			header.Set("Retry-After", ceilSeconds(res.retryAfter))
+			if opts.Handler != nil {
+				opts.Handler(c)
+				return
+			}
+			c.Problem(&HTTPError{Status: http.StatusTooManyRequests})
+		})
+	}
+}
+
+// tokenBucket counts a request with a bucket of Limit tokens, refilled at Limit tokens by Window.
+func (o *RateLimitOptions) tokenBucket(s *RateLimitState, now time.Time) (res rateLimitResult) {
+	limit := float64(o.Limit)
+	rate := limit / float64(o.Window) // Tokens by nanosecond.
+	if s.Time.IsZero() {
+		s.Count = limit
+	} else {
+		s.Count = math.Min(limit, s.Count+float64(now.Sub(s.Time))*rate)
+	}
+	s.Time = now
+	if s.Count >= 1 {
+		s.Count--
+		res.allowed = true
+	} else {
+		res.retryAfter = time.Duration((1 - s.Count) / rate)
+	}
+	res.remaining = int(s.Count)
+	res.reset = time.Duration((limit - s.Count) / rate)
+	return res
+}
+
+// slidingWindow counts a request in fixed windows, estimating the count over the last Window with a weighted previous window.
+func (o *RateLimitOptions) slidingWindow(s *RateLimitState, now time.Time) (res rateLimitResult) {
+	limit := float64(o.Limit)
+	start := now.Truncate(o.Window)
+	if !s.Time.Equal(start) {
+		if s.Time.Equal(start.Add(-o.Window)) {
+			s.Previous = s.Count
+		} else {
+			s.Previous = 0
+		}
+		s.Count, s.Time = 0, start
+	}
+	elapsed := float64(now.Sub(start)) / float64(o.Window)
+	estimated := s.Previous*(1-elapsed) + s.Count
+	res.reset = start.Add(o.Window).Sub(now)
+	if estimated+1 <= limit {
+		s.Count++
+		estimated++
+		res.allowed = true
+	} else if s.Count+1 > limit || s.Previous == 0 {
+		res.retryAfter = res.reset
+	} else { // Wait until enough of the previous window has slid out.
+		res.retryAfter = time.Duration((1-(limit-s.Count-1)/s.Previous-elapsed)*float64(o.Window)) + 1
+	}
+	res.remaining = int(math.Max(0, limit-math.Ceil(estimated)))
+	return res
+}
+
+// ceilSeconds returns d in seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
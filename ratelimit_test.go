@@ -0,0 +1,116 @@
+package app_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func rateLimitRequest(ip string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = ip + ":1234"
+	return req
+}
+
+func TestRateLimit(t *testing.T) {
+	for name, alg := range map[string]app.RateLimitAlgorithm{"token bucket": app.TokenBucket, "sliding window": app.SlidingWindow} {
+		t.Run(name, func(t *testing.T) {
+			a := app.New()
+			a.Post("/login", func(c *app.Context) {
+				c.Text("welcome")
+			}, app.RateLimit(&app.RateLimitOptions{Limit: 3, Window: time.Hour, Algorithm: alg}))
+			a.Get("/", func(c *app.Context) {
+				c.Text("home")
+			})
+
+			c := apptest.New(t, a)
+			for i := 2; i >= 0; i-- {
+				c.Do(rateLimitRequest("192.0.2.1")).
+					Status(http.StatusOK).
+					Header("RateLimit-Limit", "3").
+					Header("RateLimit-Remaining", strconv.Itoa(i))
+			}
+			r := c.Do(rateLimitRequest("192.0.2.1")).
+				Status(http.StatusTooManyRequests).
+				Header("Content-Type", "application/problem+json").
+				Header("RateLimit-Remaining", "0")
+			if r.Recorder.Result().Header.Get("Retry-After") == "" || r.Recorder.Result().Header.Get("RateLimit-Reset") == "" {
+				t.Errorf("rate limited response: want Retry-After and RateLimit-Reset headers, got %v", r.Recorder.Result().Header)
+			}
+			c.Do(rateLimitRequest("192.0.2.2")).Status(http.StatusOK)
+			c.Get("/").Status(http.StatusOK).Header("RateLimit-Limit", "")
+		})
+	}
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	a := app.New()
+	api := a.Group("/api",
+		app.BearerTokenFunc(func(_ *app.Context, token string) *app.Principal {
+			return &app.Principal{ID: token}
+		}),
+		app.RateLimit(&app.RateLimitOptions{
+			Limit:  1,
+			Window: time.Minute,
+			Key:    app.RateLimitByUser,
+			Handler: func(c *app.Context) {
+				c.Status(http.StatusTooManyRequests)
+				c.Text("slow down")
+			},
+		}),
+	)
+	api.Get("/items", func(c *app.Context) {
+		c.Text("items")
+	})
+	api.Get("/orders", func(c *app.Context) {
+		c.Text("orders")
+	}, app.RateLimit(&app.RateLimitOptions{Limit: 1, Window: time.Minute, Key: app.RateLimitByRoute}))
+
+	c := apptest.New(t, a)
+	c.Do(authRequestPath("/api/items", "alice")).Status(http.StatusOK).Header("Retry-After", "")
+	c.Do(authRequestPath("/api/items", "alice")).Status(http.StatusTooManyRequests).Body("slow down").Header("Retry-After", "60")
+	c.Do(authRequestPath("/api/orders", "bob")).Status(http.StatusOK)
+	c.Do(authRequestPath("/api/orders", "carol")).Status(http.StatusTooManyRequests).Header("Content-Type", "application/problem+json")
+}
+
+func TestRateLimitByRouteTrailingSlash(t *testing.T) {
+	a := app.New()
+	a.Get("/items", func(c *app.Context) {
+		c.Text("items")
+	})
+
+	c := apptest.New(t, a, app.RateLimit(&app.RateLimitOptions{Limit: 10, Window: time.Minute, Key: app.RateLimitByRoute}))
+	c.FollowRedirects = false
+	c.Get("/items/").Status(http.StatusMovedPermanently).Header("Location", "/items")
+	c.Get("/items").Status(http.StatusOK).Body("items")
+}
+
+func authRequestPath(path, token string) *http.Request {
+	req := authRequest("Bearer " + token)
+	req.URL.Path = path
+	return req
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Update(string, time.Duration, func(*app.RateLimitState)) error {
+	return errors.New("store down")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	a := app.New()
+	a.Post("/login", func(c *app.Context) {
+		c.Text("welcome")
+	}, app.RateLimit(&app.RateLimitOptions{Limit: 1, Window: time.Minute, Store: failingRateLimitStore{}}))
+
+	c := apptest.New(t, a)
+	c.PostForm("/login", url.Values{}).Status(http.StatusOK)
+	c.PostForm("/login", url.Values{}).Status(http.StatusOK)
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// An Encoder writes values for a media type, used by Context.Respond.
+type Encoder interface {
+	MediaType() string                       // MediaType returns the media type written by the encoder, like "application/json".
+	Encode(w io.Writer, v interface{}) error // Encode writes v to w.
+}
+
+// EncoderFunc makes an Encoder from a media type and an encoding function.
+func EncoderFunc(mediaType string, f func(w io.Writer, v interface{}) error) Encoder {
+	return &funcEncoder{mediaType, f}
+}
+
+type funcEncoder struct {
+	mediaType string
+	f         func(io.Writer, interface{}) error
+}
+
+func (e *funcEncoder) MediaType() string {
+	return e.mediaType
+}
+
+func (e *funcEncoder) Encode(w io.Writer, v interface{}) error {
+	return e.f(w, v)
+}
+
+// Built-in encoders, registered for all apps in this order.
+// Each one uses the value returned by a specific method of v if it exists, like the JSON() interface{} method for Context.JSON.
+var (
+	// EncoderJSON writes JSON. If v has a JSON() interface{} method, it will be used.
+	EncoderJSON = EncoderFunc("application/json", func(w io.Writer, v interface{}) error {
+		if vv, ok := v.(interface{ JSON() interface{} }); ok {
+			v = vv.JSON()
+		}
+		return json.NewEncoder(w).Encode(v)
+	})
+
+	// EncoderXML writes XML. If v has a XML() interface{} method, it will be used.
+	// A slice or an array is wrapped in an <items> root element, each value in an <item> element.
+	// Maps are not supported.
+	EncoderXML = EncoderFunc("application/xml", func(w io.Writer, v interface{}) error {
+		if vv, ok := v.(interface{ XML() interface{} }); ok {
+			v = vv.XML()
+		}
+		switch rv := reflect.Indirect(reflect.ValueOf(v)); rv.Kind() {
+		case reflect.Map:
+			return fmt.Errorf("app: cannot encode %T as XML", v)
+		case reflect.Slice, reflect.Array:
+			if rv.Type().Elem().Kind() != reflect.Uint8 { // Bytes are encoded as text.
+				v = xmlItems{Items: v}
+			}
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(v)
+	})
+
+	// EncoderYAML writes YAML. If v has a YAML() interface{} method, it will be used.
+	EncoderYAML = EncoderFunc("application/yaml", func(w io.Writer, v interface{}) error {
+		if vv, ok := v.(interface{ YAML() interface{} }); ok {
+			v = vv.YAML()
+		}
+		return yaml.NewEncoder(w).Encode(v)
+	})
+
+	// EncoderMsgPack writes MessagePack. If v has a MsgPack() interface{} method, it will be used.
+	// Struct fields use the json tags when they have no msgpack tag.
+	EncoderMsgPack = EncoderFunc("application/msgpack", func(w io.Writer, v interface{}) error {
+		if vv, ok := v.(interface{ MsgPack() interface{} }); ok {
+			v = vv.MsgPack()
+		}
+		return msgpack.NewEncoder(w).UseJSONTag(true).Encode(v)
+	})
+
+	// EncoderCSV writes CSV. If v has a CSV() [][]string method, it will be used.
+	// Otherwise, v must be a [][]string or a slice of structs: the header is made from the field names (or their csv tags) and the values are formatted with fmt.
+	EncoderCSV = EncoderFunc("text/csv", func(w io.Writer, v interface{}) error {
+		records, err := csvRecords(v)
+		if err != nil {
+			return err
+		}
+		return csv.NewWriter(w).WriteAll(records)
+	})
+)
+
+// xmlItems is the root element of a slice encoded by EncoderXML.
+type xmlItems struct {
+	XMLName xml.Name    `xml:"items"`
+	Items   interface{} `xml:"item"`
+}
+
+// AddEncoder registers an encoder for Context.Respond.
+// An encoder for the same media type is replaced.
+// When the client accepts any type, the first registered encoder is used (JSON by default).
+func (a *App) AddEncoder(e Encoder) {
+	for i, ae := range a.encoders {
+		if ae.MediaType() == e.MediaType() {
+			a.encoders[i] = e
+			return
+		}
+	}
+	a.encoders = append(a.encoders, e)
+}
+
+// AddEncoder registers an encoder for Context.Respond.
+// An encoder for the same media type is replaced.
+// When the client accepts any type, the first registered encoder is used (JSON by default).
+func AddEncoder(e Encoder) {
+	defaultApp.AddEncoder(e)
+}
+
+// Respond writes the response with v encoded in the format accepted by the client (from the "Accept" header), among the registered encoders.
+// If no encoder is acceptable, the response is a "406 Not Acceptable".
+func (c *Context) Respond(v interface{}) {
+	c.Res.Header().Add("Vary", "Accept")
+	enc := negotiateEncoder(c.app().encoders, c.Req.Header.Get("Accept"))
+	if enc == nil {
+		http.Error(c.Res, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	ct := enc.MediaType()
+	if strings.HasPrefix(ct, "text/") {
+		ct += "; charset=utf-8"
+	}
+	c.Res.Header().Set("Content-Type", ct)
+	if err := enc.Encode(c.Res, v); err != nil {
+		c.Panic(err)
+	}
+}
+
+// acceptRange is a media range from an "Accept" header.
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept returns the media ranges of an "Accept" header, sorted by preference.
+// Ranges with a zero quality are kept (last) as they refuse their types.
+func parseAccept(accept string) []acceptRange {
+	var rr []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		r := acceptRange{q: 1}
+		if q, ok := params["q"]; ok {
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if r.q < 0 {
+			continue
+		}
+		if i := strings.IndexByte(mt, '/'); i != -1 {
+			r.typ, r.subtype = mt[:i], mt[i+1:]
+		} else {
+			r.typ, r.subtype = mt, "*"
+		}
+		rr = append(rr, r)
+	}
+	sort.SliceStable(rr, func(i, j int) bool {
+		if rr[i].q != rr[j].q {
+			return rr[i].q > rr[j].q
+		}
+		return rr[i].specificity() > rr[j].specificity()
+	})
+	return rr
+}
+
+// specificity tells how specific the range is: */* < type/* < type/subtype.
+func (r acceptRange) specificity() int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// match tells if the range matches the media type.
+func (r acceptRange) match(mediaType string) bool {
+	i := strings.IndexByte(mediaType, '/')
+	typ, subtype := mediaType[:i], mediaType[i+1:]
+	return (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype)
+}
+
+// mediaRange returns the most specific range matching the media type, and false if there is none.
+// Its quality is the one of the media type: zero if it's refused.
+func mediaRange(rr []acceptRange, mediaType string) (acceptRange, bool) {
+	var best acceptRange
+	found := false
+	for _, r := range rr {
+		if r.match(mediaType) && (!found || r.specificity() > best.specificity()) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// negotiateEncoder returns the encoder preferred by the "Accept" header, or nil if none is acceptable.
+// An encoder is preferred for the quality of its type, then for the specificity of the range giving it, then for its order.
+// Without header, the first encoder is used.
+func negotiateEncoder(encoders []Encoder, accept string) Encoder {
+	if len(encoders) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return encoders[0]
+	}
+	rr := parseAccept(accept)
+	var best Encoder
+	var bestRange acceptRange
+	for _, e := range encoders {
+		r, ok := mediaRange(rr, e.MediaType())
+		if !ok || r.q <= 0 {
+			continue
+		}
+		if best == nil || r.q > bestRange.q || r.q == bestRange.q && r.specificity() > bestRange.specificity() {
+			best, bestRange = e, r
+		}
+	}
+	return best
+}
+
+// csvRecords returns the CSV records for v.
+func csvRecords(v interface{}) ([][]string, error) {
+	if vv, ok := v.(interface{ CSV() [][]string }); ok {
+		return vv.CSV(), nil
+	}
+	if records, ok := v.([][]string); ok {
+		return records, nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("app: cannot encode %T as CSV", v)
+	}
+	et := rv.Type().Elem()
+	for et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("app: cannot encode %T as CSV", v)
+	}
+
+	var fields []int
+	var header []string
+	for i := 0; i < et.NumField(); i++ {
+		sf := et.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag := sf.Tag.Get("csv"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+
+	records := make([][]string, 0, rv.Len()+1)
+	records = append(records, header)
+	for i := 0; i < rv.Len(); i++ {
+		ev := reflect.Indirect(rv.Index(i))
+		record := make([]string, len(fields))
+		if ev.IsValid() {
+			for j, f := range fields {
+				record[j] = fmt.Sprint(ev.Field(f).Interface())
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,66 @@
+package app_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+type respondUser struct {
+	ID   int    `json:"id" xml:"id" yaml:"id" csv:"id"`
+	Name string `json:"name" xml:"name" yaml:"name" csv:"name"`
+}
+
+func TestRespond(t *testing.T) {
+	a := app.New()
+	a.Get("/users", func(c *app.Context) {
+		c.Respond([]respondUser{{1, "Ann"}, {2, "Bob"}})
+	})
+	a.AddEncoder(app.EncoderFunc("text/plain", func(w io.Writer, v interface{}) error {
+		_, err := io.WriteString(w, "users")
+		return err
+	}))
+
+	c := apptest.New(t, a)
+	res := c.Get("/users").
+		Header("Content-Type", "application/json").
+		JSON([]respondUser{{1, "Ann"}, {2, "Bob"}})
+	if vary := strings.Join(res.Recorder.Result().Header["Vary"], ", "); !strings.Contains(", "+vary+", ", ", Accept, ") {
+		t.Errorf("Vary: want to contain %q, got %q", "Accept", vary)
+	}
+
+	for accept, want := range map[string]string{
+		"application/xml":                           "<items><item><id>1</id><name>Ann</name></item><item><id>2</id><name>Bob</name></item></items>",
+		"application/yaml":                          "- id: 1\n  name: Ann\n",
+		"text/csv":                                  "id,name\n1,Ann\n2,Bob\n",
+		"text/*;q=0.5, application/json;q=0.1":      "id,name\n",
+		"text/plain, text/csv;q=0.9":                "users",
+		"application/msgpack, application/json;q=0": "\x92",
+		"application/json;q=0, */*":                 "<items>",
+		"text/plain;q=0, text/*":                    "id,name\n",
+		"text/*;q=0.8, text/csv;q=0, */*;q=0.1":     "users",
+	} {
+		c.SetHeader("Accept", accept)
+		res := c.Get("/users").Status(http.StatusOK)
+		if b := res.Recorder.Body.String(); !strings.Contains(b, want) {
+			t.Errorf("Accept %q: body %q doesn't contain %q", accept, b, want)
+		}
+	}
+
+	c.SetHeader("Accept", "image/png")
+	c.Get("/users").Status(http.StatusNotAcceptable)
+}
+
+func TestRespondXMLMap(t *testing.T) {
+	a := app.New()
+	a.Get("/", func(c *app.Context) {
+		c.Respond(map[string]int{"users": 2})
+	})
+	c := apptest.New(t, a)
+	c.SetHeader("Accept", "application/xml")
+	c.Get("/").Status(http.StatusInternalServerError)
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// A RouteEntry is a registered route.
+type RouteEntry struct {
+	app         *App
+	group       *RouterGroup
+	method      string
+	path        string
+	name        string
+	handler     http.Handler
+	middlewares []string // middlewares are the middleware names, in wrapping order.
+	skipCSRF    bool
+	policies    []Policy
+	corsOptions *CORSOptions
+}
+
+// A RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"` // Path is the full route pattern, with group prefixes.
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`            // Handler is the handler function name.
+	Middlewares []string `json:"middlewares"`        // Middlewares are the middleware function names, in wrapping order.
+	Policies    []string `json:"policies,omitempty"` // Policies are the authorization policies (see RouteEntry.Authorize).
+}
+
+// handle registers the route for method (or methodAny) and path.
+func (a *App) handle(method, path string, handler http.Handler, middlewares []Middleware) *RouteEntry {
+	methods := []string{method}
+	if method == methodAny {
+		methods = anyMethods
+	}
+	re := &RouteEntry{app: a, method: method, path: path, handler: handler, middlewares: make([]string, len(middlewares))}
+	h := routeHandler(re, handler)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+		re.middlewares[i] = middlewareName(middlewares[i], h)
+	}
+	for _, m := range methods {
+		a.rt.Handle(m, path, h)
+		a.probe(m, re)
+	}
+	a.routes = append(a.routes, re)
+	return re
+}
+
+// routeHandler wraps the handler of route re, just after its middlewares.
+// It keeps the route in the debug state, checks the route policies and checks the CSRF token if the request is protected.
+func routeHandler(re *RouteEntry, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &Context{Res: w, Req: r}
+		if ds := c.debug(); ds != nil {
+			ds.req = r
+			ds.route = re
+		}
+		if !re.authorize(c) {
+			return
+		}
+		if o, ok := c.Get(contextKeyCSRF).(*CSRFOptions); ok && !re.skipCSRF && !c.checkCSRF(o) {
+			c.serveCSRFFailure(o)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Routes returns the registered routes, in registration order.
+func (a *App) Routes() []RouteInfo {
+	ri := make([]RouteInfo, 0, len(a.routes))
+	for _, re := range a.routes {
+		var pp []string
+		for _, p := range re.policiesChain() {
+			pp = append(pp, p.String())
+		}
+		ri = append(ri, RouteInfo{
+			Method:      re.method,
+			Path:        re.path,
+			Name:        re.name,
+			Handler:     handlerName(re.handler),
+			Middlewares: append([]string{}, re.middlewares...),
+			Policies:    pp,
+		})
+	}
+	return ri
+}
+
+// Routes returns the registered routes, in registration order.
+func Routes() []RouteInfo {
+	return defaultApp.Routes()
+}
+
+// A namedHandler is a handler made by a middleware constructor of this package, keeping the constructor name for the routes listing.
+type namedHandler struct {
+	http.Handler
+	name string
+}
+
+// middlewareName returns the name of the constructor of middleware m if it's made by this package, its function name otherwise.
+// Handler h is the one returned by m.
+func middlewareName(m Middleware, h http.Handler) string {
+	if nh, ok := h.(namedHandler); ok {
+		return nh.name
+	}
+	return funcName(m)
+}
+
+// handlerName returns the function name of h if it's a function, its type otherwise.
+func handlerName(h http.Handler) string {
+	if reflect.TypeOf(h).Kind() == reflect.Func {
+		return funcName(h)
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// funcName returns the full name of function f.
+func funcName(f interface{}) string {
+	if rf := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); rf != nil {
+		return rf.Name()
+	}
+	return "?"
+}
+
+// Name sets the route name, used to build its URL with App.URL or the "url" view function.
+func (re *RouteEntry) Name(name string) *RouteEntry {
+	if re.name != "" {
+		panic(fmt.Errorf("app: route %s %s named multiple times", re.method, re.path))
+	}
+	if _, ok := re.app.namedRoutes[name]; ok {
+		panic(fmt.Errorf("app: route name %q set multiple times", name))
+	}
+	re.name = name
+	re.app.namedRoutes[name] = re
+	return re
+}
+
+// URL returns the path of the named route, with params filled.
+// Params are given by key/value pairs: path parameters (like :id) take their values from them and the others are added to the query string.
+// The wildcard parameter is set with key "*".
+//
+// It panics if the route doesn't exist, if a path parameter is missing or if a value doesn't match its regular expression.
+func (a *App) URL(name string, params ...string) string {
+	u, err := a.url(name, params...)
+	if err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+	return u
+}
+
+// URL returns the path of the named route, with params filled.
+// Params are given by key/value pairs: path parameters (like :id) take their values from them and the others are added to the query string.
+// The wildcard parameter is set with key "*".
+//
+// It panics if the route doesn't exist, if a path parameter is missing or if a value doesn't match its regular expression.
+func URL(name string, params ...string) string {
+	return defaultApp.URL(name, params...)
+}
+
+// url builds the path of the named route with params filled.
+func (a *App) url(name string, params ...string) (string, error) {
+	re, ok := a.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("no route named %q", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: odd number of params", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	parts := strings.Split(re.path[1:], "/")
+	for i, part := range parts {
+		b.WriteByte('/')
+		if i == len(parts)-1 && part == "" { // Trailing slash: wildcard.
+			if v, ok := values["*"]; ok {
+				b.WriteString(escapePathSegments(v))
+				delete(values, "*")
+			}
+			break
+		}
+		if len(part) == 0 || part[0] != ':' {
+			b.WriteString(part)
+			continue
+		}
+		param, res := part[1:], ""
+		if sep := strings.IndexByte(param, ':'); sep != -1 {
+			param, res = param[:sep], param[sep+1:]
+		}
+		if param == "" {
+			return "", fmt.Errorf("route %q: anonymous parameter can't be filled", name)
+		}
+		v, ok := values[param]
+		if !ok {
+			return "", fmt.Errorf("route %q: missing parameter %q", name, param)
+		}
+		if res != "" && !regexp.MustCompile(res).MatchString(v) {
+			return "", fmt.Errorf("route %q: parameter %q value %q doesn't match %q", name, param, v, res)
+		}
+		b.WriteString(url.PathEscape(v))
+		delete(values, param)
+	}
+
+	if len(values) > 0 {
+		q := make(url.Values, len(values))
+		for k, v := range values {
+			q.Set(k, v)
+		}
+		b.WriteByte('?')
+		b.WriteString(q.Encode())
+	}
+	return b.String(), nil
+}
+
+// escapePathSegments escapes each segment of path p.
+func escapePathSegments(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,118 @@
+package app_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gowww/app"
+)
+
+func TestURL(t *testing.T) {
+	a := app.New()
+	h := func(c *app.Context) {}
+	a.Get(`/users/:id:^\d+$`, h).Name("user")
+	a.Group("/files").Get("/", h).Name("files")
+	a.Group("/api").Group("/v1").Get("/posts/:slug/comments", h).Name("comments")
+
+	cases := []struct {
+		name   string
+		params []string
+		want   string
+	}{
+		{"user", []string{"id", "42"}, "/users/42"},
+		{"user", []string{"id", "42", "tab", "posts"}, "/users/42?tab=posts"},
+		{"files", []string{"*", "a b/c.txt"}, "/files/a%20b/c.txt"},
+		{"files", nil, "/files/"},
+		{"comments", []string{"slug", "hello", "page", "2"}, "/api/v1/posts/hello/comments?page=2"},
+	}
+	for _, c := range cases {
+		if got := a.URL(c.name, c.params...); got != c.want {
+			t.Errorf("URL(%q, %q): want %q, got %q", c.name, c.params, c.want, got)
+		}
+	}
+
+	panics := [][]string{
+		{"user"},
+		{"user", "id", "abc"},
+		{"user", "id"},
+		{"unknown"},
+	}
+	for _, p := range panics {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("URL(%q): want panic", p)
+				}
+			}()
+			a.URL(p[0], p[1:]...)
+		}()
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	a := app.New()
+	a.Group("/admin", auth).Get("/users/:id", func(c *app.Context) {}).Name("adminUser")
+
+	routes := a.Routes()
+	if len(routes) != 2 { // Static files route comes first.
+		t.Fatalf("routes: want 2, got %d", len(routes))
+	}
+	r := routes[1]
+	if r.Method != "GET" || r.Path != "/admin/users/:id" || r.Name != "adminUser" {
+		t.Errorf("route: want GET /admin/users/:id adminUser, got %s %s %s", r.Method, r.Path, r.Name)
+	}
+	if r.Handler != "github.com/gowww/app_test.TestRoutes.func1" {
+		t.Errorf("route handler: got %q", r.Handler)
+	}
+	if len(r.Middlewares) != 1 || r.Middlewares[0] != "github.com/gowww/app_test.auth" {
+		t.Errorf("route middlewares: want [github.com/gowww/app_test.auth], got %v", r.Middlewares)
+	}
+}
+
+// TestRoutesFlag runs the test binary again with flag -routes, where TestRoutesFlagProgram acts as a program with two apps.
+func TestRoutesFlag(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRoutesFlagProgram$", "-routes")
+	cmd.Env = append(os.Environ(), "APP_ROUTES_PROGRAM=1")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running with flag -routes: %v", err)
+	}
+	paths := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var table struct {
+			Address string          `json:"address"`
+			Routes  []app.RouteInfo `json:"routes"`
+		}
+		if err = json.Unmarshal([]byte(line), &table); err != nil {
+			t.Fatalf("routes table %q: %v", line, err)
+		}
+		for _, ri := range table.Routes {
+			paths[ri.Path] = table.Address
+		}
+	}
+	if len(paths) != 3 || paths["/admin"] != ":8081" || paths["/home"] != ":8080" {
+		t.Errorf("routes tables: want /admin on :8081 and /home on :8080, got %v", paths)
+	}
+}
+
+func TestRoutesFlagProgram(t *testing.T) {
+	if os.Getenv("APP_ROUTES_PROGRAM") == "" {
+		t.Skip("only run by TestRoutesFlag")
+	}
+	h := func(c *app.Context) {}
+	admin := app.New()
+	admin.SetAddress(":8081")
+	admin.Get("/admin", h)
+	a := app.New()
+	a.SetAddress(":8080")
+	a.Get("/home", h)
+
+	go admin.Run()
+	a.Run()
+}
@@ -0,0 +1,260 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Session defaults.
+const (
+	defaultSessionCookieName  = "session"
+	defaultSessionIdleTimeout = 30 * time.Minute
+	defaultSessionMaxLifetime = 24 * time.Hour
+)
+
+// SessionOptions are the options of the sessions.
+type SessionOptions struct {
+	Store       SessionStore  // Store keeps the sessions. If not set, a CookieSessionStore is used with the app secret.
+	CookieName  string        // CookieName is the name of the session cookie ("session" by default).
+	IdleTimeout time.Duration // IdleTimeout is the inactivity duration after which the session expires (30 minutes by default).
+	MaxLifetime time.Duration // MaxLifetime is the duration after which the session expires, even when active (24 hours by default).
+}
+
+// Sessions sets the session options.
+// Without them, sessions are kept in an encrypted cookie (see CookieSessionStore), requiring the app secret.
+func (a *App) Sessions(o *SessionOptions) {
+	if a.sessionOptions != nil {
+		panic("app: session options set multiple times")
+	}
+	a.sessionOptions = o
+}
+
+// Sessions sets the session options.
+// Without them, sessions are kept in an encrypted cookie (see CookieSessionStore), requiring the app secret.
+func Sessions(o *SessionOptions) {
+	defaultApp.Sessions(o)
+}
+
+// sessionConfig returns the session options with defaults set.
+func (a *App) sessionConfig() SessionOptions {
+	var o SessionOptions
+	if a.sessionOptions != nil {
+		o = *a.sessionOptions
+	}
+	if o.Store == nil {
+		if a.encrypter == nil {
+			panic("app: no session store and no secret key for the cookie session store")
+		}
+		o.Store = NewCookieSessionStore(a.encrypter)
+	}
+	if o.CookieName == "" {
+		o.CookieName = defaultSessionCookieName
+	}
+	if o.IdleTimeout == 0 {
+		o.IdleTimeout = defaultSessionIdleTimeout
+	}
+	if o.MaxLifetime == 0 {
+		o.MaxLifetime = defaultSessionMaxLifetime
+	}
+	return o
+}
+
+// SessionData is the content of a session, as saved in a SessionStore.
+// Values must be encodable with encoding/gob: register your own types with gob.Register.
+type SessionData struct {
+	ID       string
+	Values   map[string]interface{}
+	Created  time.Time // Created is the session start, for the maximum lifetime.
+	Accessed time.Time // Accessed is the last use of the session, for the idle timeout.
+	Expires  time.Time // Expires is the time after which the session must not be loaded.
+
+	stale bool // stale tells the session must be saved again, like when it has been decrypted with an old key.
+}
+
+// A Session is a client session, loaded on first use and saved before the response is written.
+// It works like a typed map of values.
+type Session struct {
+	data     *SessionData
+	cookie   string // cookie is the cookie value the session has been loaded from.
+	fresh    bool   // fresh tells the session is not in the store: it's saved only if it's modified or renewed.
+	modified bool
+	renewed  bool
+	deleted  bool
+}
+
+// ID returns the session ID.
+func (s *Session) ID() string {
+	return s.data.ID
+}
+
+// Get returns the value for key, or nil.
+func (s *Session) Get(key string) interface{} {
+	return s.data.Values[key]
+}
+
+// Has tells if the session contains key.
+func (s *Session) Has(key string) bool {
+	_, ok := s.data.Values[key]
+	return ok
+}
+
+// String returns the string value for key, or an empty string.
+func (s *Session) String(key string) string {
+	v, _ := s.data.Values[key].(string)
+	return v
+}
+
+// Int returns the int value for key, or 0.
+func (s *Session) Int(key string) int {
+	v, _ := s.data.Values[key].(int)
+	return v
+}
+
+// Bool returns the bool value for key, or false.
+func (s *Session) Bool(key string) bool {
+	v, _ := s.data.Values[key].(bool)
+	return v
+}
+
+// Set sets the value for key.
+func (s *Session) Set(key string, v interface{}) {
+	s.data.Values[key] = v
+	s.modified = true
+}
+
+// Delete removes the value for key.
+func (s *Session) Delete(key string) {
+	delete(s.data.Values, key)
+	s.modified = true
+}
+
+// Clear removes all the values.
+func (s *Session) Clear() {
+	s.data.Values = make(map[string]interface{})
+	s.modified = true
+}
+
+// RenewID gives a new ID to the session, keeping its values.
+// It must be called when the privileges change (like on login) to prevent session fixation.
+func (s *Session) RenewID() {
+	s.data.ID = newSessionID()
+	s.renewed = true
+}
+
+// Destroy removes the session from the store and the client.
+// A new session is started if it's used again while serving the request.
+func (s *Session) Destroy() {
+	s.deleted = true
+}
+
+// newSessionID returns a new random session ID.
+func newSessionID() string {
+	return randomHex(32)
+}
+
+// newSession returns an empty session.
+func newSession() *Session {
+	now := time.Now()
+	return &Session{
+		data: &SessionData{
+			ID:       newSessionID(),
+			Values:   make(map[string]interface{}),
+			Created:  now,
+			Accessed: now,
+		},
+		fresh: true,
+	}
+}
+
+// A sessionHolder keeps the session of a request, shared by all its contexts.
+type sessionHolder struct {
+	session *Session
+}
+
+// Session returns the client session, loaded on first use and saved before the response is written.
+// An expired or unknown session is replaced by a new one.
+func (c *Context) Session() *Session {
+	holder, ok := c.Get(contextKeySession).(*sessionHolder)
+	if !ok {
+		panic("app: session used outside an app handler")
+	}
+	if holder.session != nil && !holder.session.deleted {
+		return holder.session
+	}
+	o := c.app().sessionConfig()
+	if holder.session != nil { // Destroyed during this request.
+		old := holder.session
+		holder.session = newSession()
+		holder.session.cookie = old.cookie
+		return holder.session
+	}
+	holder.session = newSession()
+	ck, _ := c.Req.Cookie(o.CookieName)
+	if ck == nil {
+		return holder.session
+	}
+	holder.session.cookie = ck.Value
+	data, err := o.Store.Load(ck.Value)
+	if err != nil {
+		c.Panic(err)
+	}
+	now := time.Now()
+	if data == nil || now.After(data.Expires) || now.Sub(data.Accessed) > o.IdleTimeout || now.Sub(data.Created) > o.MaxLifetime {
+		return holder.session // The fresh session replaces the stale one, removed from the store.
+	}
+	if data.Values == nil {
+		data.Values = make(map[string]interface{})
+	}
+	holder.session.data = data
+	holder.session.fresh = false
+	holder.session.modified = data.stale
+	return holder.session
+}
+
+// saveSession saves the session of the request (if it has been used) and sets its cookie.
+// A fresh session is saved only if it has been modified or renewed, so reading the session of an anonymous client doesn't store anything.
+// It's called just before the response header is written.
+func (a *App) saveSession(w http.ResponseWriter, holder *sessionHolder) {
+	s := holder.session
+	if s == nil {
+		return
+	}
+	o := a.sessionConfig()
+	if (s.deleted || s.renewed || s.fresh) && s.cookie != "" {
+		if err := o.Store.Delete(s.cookie); err != nil {
+			log.Printf("Deleting session: %v", err)
+		}
+	}
+	if s.deleted || s.fresh && !s.modified && !s.renewed {
+		if s.cookie == "" {
+			return
+		}
+		a.deleteCookie(w, &http.Cookie{Name: o.CookieName})
+		return
+	}
+	touch := o.IdleTimeout / 10 // Avoid saving an unchanged session on each request.
+	if touch > time.Minute {
+		touch = time.Minute
+	}
+	if !s.modified && !s.renewed && time.Since(s.data.Accessed) < touch {
+		return
+	}
+	now := time.Now()
+	s.data.Accessed = now
+	s.data.Expires = now.Add(o.IdleTimeout)
+	if end := s.data.Created.Add(o.MaxLifetime); end.Before(s.data.Expires) {
+		s.data.Expires = end
+	}
+	v, err := o.Store.Save(s.data)
+	if err != nil {
+		log.Printf("Saving session: %v", err)
+		return
+	}
+	a.setCookie(w, &http.Cookie{
+		Name:     o.CookieName,
+		Value:    v,
+		Expires:  s.data.Created.Add(o.MaxLifetime), // The idle timeout is checked server-side.
+		HttpOnly: true,
+	})
+}
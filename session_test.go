@@ -0,0 +1,103 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gowww/app"
+	"github.com/gowww/app/apptest"
+)
+
+func sessionApp(o *app.SessionOptions) *app.App {
+	a := app.New()
+	a.Secret("ab7d8ee1c25e4f6a8d8b4a9c27f1f1e3")
+	if o != nil {
+		a.Sessions(o)
+	}
+	a.Get("/", func(c *app.Context) {
+		c.Text(c.Session().String("user"))
+	})
+	a.Post("/login", func(c *app.Context) {
+		s := c.Session()
+		s.RenewID()
+		s.Set("user", c.FormValue("user"))
+	})
+	a.Post("/logout", func(c *app.Context) {
+		c.Session().Destroy()
+	})
+	return a
+}
+
+func sessionCookie(c *apptest.Client) string {
+	for _, ck := range c.Cookies() {
+		if ck.Name == "session" {
+			return ck.Value
+		}
+	}
+	return ""
+}
+
+func TestSession(t *testing.T) {
+	for name, o := range map[string]*app.SessionOptions{
+		"cookie": nil,
+		"memory": {Store: app.NewMemorySessionStore()},
+		"file":   {Store: app.NewFileSessionStore(t.TempDir())},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := apptest.New(t, sessionApp(o))
+			c.Get("/").Body("")
+			anonymous := sessionCookie(c)
+			c.PostForm("/login", url.Values{"user": {"ann"}})
+			if sessionCookie(c) == anonymous {
+				t.Error("session ID not renewed on login")
+			}
+			c.Get("/").Body("ann")
+			c.PostForm("/logout", nil)
+			c.Get("/").Body("")
+		})
+	}
+}
+
+func TestSessionRenewID(t *testing.T) {
+	a := sessionApp(&app.SessionOptions{Store: app.NewMemorySessionStore()})
+	c := apptest.New(t, a)
+	c.PostForm("/login", url.Values{"user": {"ann"}})
+	stolen := sessionCookie(c)
+	c.PostForm("/login", url.Values{"user": {"ann"}})
+	c.Get("/").Body("ann")
+
+	attacker := apptest.New(t, a)
+	attacker.SetCookie(&http.Cookie{Name: "session", Value: stolen})
+	attacker.Get("/").Body("")
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	c := apptest.New(t, sessionApp(&app.SessionOptions{Store: app.NewMemorySessionStore(), IdleTimeout: 50 * time.Millisecond}))
+	c.PostForm("/login", url.Values{"user": {"ann"}})
+	c.Get("/").Body("ann")
+	time.Sleep(100 * time.Millisecond)
+	c.Get("/").Body("")
+}
+
+func TestSessionAnonymousRead(t *testing.T) {
+	dir := t.TempDir()
+	c := apptest.New(t, sessionApp(&app.SessionOptions{Store: app.NewFileSessionStore(dir)}))
+	r := c.Get("/").Body("")
+	if len(r.Recorder.Result().Cookies()) > 0 {
+		t.Errorf("anonymous read: want no cookie, got %v", r.Recorder.Result().Cookies())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+		t.Errorf("anonymous read: want nothing stored, got %d files", len(files))
+	}
+
+	c.SetCookie(&http.Cookie{Name: "session", Value: "unknown"})
+	if ck := responseCookie(t, c.Get("/"), "session"); ck.MaxAge != -1 {
+		t.Errorf("unknown session cookie: want deleted, got %v", ck)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+		t.Errorf("unknown session read: want nothing stored, got %d files", len(files))
+	}
+}
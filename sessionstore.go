@@ -0,0 +1,242 @@
+package app
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gowww/crypto"
+)
+
+// sessionPruneInterval is the minimum duration between two removals of the expired sessions from a store.
+const sessionPruneInterval = time.Minute
+
+// maxCookieSize is the maximum size of a cookie value accepted by browsers.
+const maxCookieSize = 4096
+
+// reSessionID matches a valid session ID.
+var reSessionID = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// A SessionStore keeps the sessions.
+// The session cookie holds the value returned by Save: the session ID for a server-side store, or the whole session for a cookie store.
+type SessionStore interface {
+	Load(value string) (*SessionData, error) // Load returns the session for a cookie value, or nil if it doesn't exist or has expired.
+	Save(data *SessionData) (string, error)  // Save stores the session and returns the cookie value.
+	Delete(value string) error               // Delete removes the session for a cookie value.
+}
+
+// MemorySessionStore keeps the sessions in memory.
+// They are lost when the app stops and not shared between multiple instances.
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]*SessionData
+	lastPrune time.Time
+}
+
+// NewMemorySessionStore returns a new in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*SessionData), lastPrune: time.Now()}
+}
+
+// Load implements SessionStore.
+func (st *MemorySessionStore) Load(id string) (*SessionData, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	data, ok := st.sessions[id]
+	if !ok || time.Now().After(data.Expires) {
+		return nil, nil
+	}
+	return copySessionData(data), nil
+}
+
+// Save implements SessionStore.
+func (st *MemorySessionStore) Save(data *SessionData) (string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sessions[data.ID] = copySessionData(data)
+	if now := time.Now(); now.Sub(st.lastPrune) > sessionPruneInterval {
+		for id, data := range st.sessions {
+			if now.After(data.Expires) {
+				delete(st.sessions, id)
+			}
+		}
+		st.lastPrune = now
+	}
+	return data.ID, nil
+}
+
+// Delete implements SessionStore.
+func (st *MemorySessionStore) Delete(id string) error {
+	st.mu.Lock()
+	delete(st.sessions, id)
+	st.mu.Unlock()
+	return nil
+}
+
+// copySessionData returns a copy of data, with its own values map.
+func copySessionData(data *SessionData) *SessionData {
+	cp := *data
+	cp.Values = make(map[string]interface{}, len(data.Values))
+	for k, v := range data.Values {
+		cp.Values[k] = v
+	}
+	return &cp
+}
+
+// FileSessionStore keeps the sessions in files of a directory, one per session.
+type FileSessionStore struct {
+	dir       string
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewFileSessionStore returns a new session store using directory dir, created if needed.
+func NewFileSessionStore(dir string) *FileSessionStore {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		panic(fmt.Errorf("app: %v", err))
+	}
+	return &FileSessionStore{dir: dir, lastPrune: time.Now()}
+}
+
+// Load implements SessionStore.
+func (st *FileSessionStore) Load(id string) (*SessionData, error) {
+	if !reSessionID.MatchString(id) {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(st.dir, id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeSessionData(b)
+	if err != nil || time.Now().After(data.Expires) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Save implements SessionStore.
+// The file modification time is set to the session expiry, allowing to prune expired sessions without decoding them.
+func (st *FileSessionStore) Save(data *SessionData) (string, error) {
+	b, err := encodeSessionData(data)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(st.dir, data.ID)
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		return "", err
+	}
+	if err = os.Chtimes(path, data.Expires, data.Expires); err != nil {
+		return "", err
+	}
+	st.prune()
+	return data.ID, nil
+}
+
+// Delete implements SessionStore.
+func (st *FileSessionStore) Delete(id string) error {
+	if !reSessionID.MatchString(id) {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(st.dir, id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// prune removes the expired session files, at most once per sessionPruneInterval.
+func (st *FileSessionStore) prune() {
+	st.mu.Lock()
+	now := time.Now()
+	if now.Sub(st.lastPrune) < sessionPruneInterval {
+		st.mu.Unlock()
+		return
+	}
+	st.lastPrune = now
+	st.mu.Unlock()
+	files, err := ioutil.ReadDir(st.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if reSessionID.MatchString(f.Name()) && now.After(f.ModTime()) {
+			os.Remove(filepath.Join(st.dir, f.Name()))
+		}
+	}
+}
+
+// CookieSessionStore keeps the sessions in the client cookie, encrypted.
+// Nothing is kept server-side: a session can't be invalidated before its expiry and its size is limited to 4 KB once encrypted.
+type CookieSessionStore struct {
+	encrypter crypto.Encrypter
+}
+
+// NewCookieSessionStore returns a new session store encrypting the sessions into the cookie with encrypter.
+// It's the default store, with the app encrypter (see App.Secret).
+func NewCookieSessionStore(encrypter crypto.Encrypter) *CookieSessionStore {
+	return &CookieSessionStore{encrypter}
+}
+
+// Load implements SessionStore.
+// A cookie that can't be decrypted is ignored.
+// A session decrypted with an old key of a Keyring is saved again, with the primary key.
+func (st *CookieSessionStore) Load(value string) (*SessionData, error) {
+	b, stale, err := decryptBase64(st.encrypter, []byte(value))
+	if err != nil {
+		return nil, nil
+	}
+	data, err := decodeSessionData(b)
+	if err != nil || time.Now().After(data.Expires) {
+		return nil, nil
+	}
+	data.stale = stale
+	return data, nil
+}
+
+// Save implements SessionStore.
+func (st *CookieSessionStore) Save(data *SessionData) (string, error) {
+	b, err := encodeSessionData(data)
+	if err != nil {
+		return "", err
+	}
+	if b, err = st.encrypter.EncryptBase64(b); err != nil {
+		return "", err
+	}
+	if len(b) > maxCookieSize {
+		return "", errors.New("app: session too large for a cookie")
+	}
+	return string(b), nil
+}
+
+// Delete implements SessionStore.
+// There is nothing to delete server-side.
+func (st *CookieSessionStore) Delete(string) error {
+	return nil
+}
+
+// encodeSessionData encodes data with gob.
+func encodeSessionData(data *SessionData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSessionData decodes a gob encoded session.
+func decodeSessionData(b []byte) (*SessionData, error) {
+	data := new(SessionData)
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
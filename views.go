@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"html/template"
+	"net/http"
 	"os"
 	"strings"
 
@@ -14,10 +16,7 @@ const (
 	staticDir = "static"
 )
 
-var (
-	staticHandler = static.Handle("/"+staticDir+"/", staticDir)
-	views         = view.New()
-)
+var staticHandler = static.Handle("/"+staticDir+"/", staticDir)
 
 // ViewData represents data for a view rendering.
 type ViewData map[string]interface{}
@@ -25,38 +24,63 @@ type ViewData map[string]interface{}
 // ViewFuncs is a map of functions passed to all view renderings.
 type ViewFuncs map[string]interface{}
 
+// A ViewHook is called with the view name and data each time a view is rendered.
+type ViewHook func(name string, data ViewData)
+
+// RequestWithViewHook returns the HTTP request with hook called each time a view is rendered while serving it.
+// It's mostly useful for testing.
+func RequestWithViewHook(r *http.Request, hook ViewHook) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKeyViewHook, hook))
+}
+
+// GlobalViewData adds global data for view templates.
+func (a *App) GlobalViewData(data ViewData) {
+	a.views.Data(view.Data(data))
+}
+
 // GlobalViewData adds global data for view templates.
 func GlobalViewData(data ViewData) {
-	views.Data(view.Data(data))
+	defaultApp.GlobalViewData(data)
+}
+
+// GlobalViewFuncs adds functions for view templates.
+func (a *App) GlobalViewFuncs(funcs ViewFuncs) {
+	a.views.Funcs(view.Funcs(funcs))
 }
 
 // GlobalViewFuncs adds functions for view templates.
 func GlobalViewFuncs(funcs ViewFuncs) {
-	views.Funcs(view.Funcs(funcs))
+	defaultApp.GlobalViewFuncs(funcs)
 }
 
-func initViews() {
-	if _, err := os.Stat(viewsDir); err != nil { // viewsDir not found: nothing to parse.
-		return
-	}
+// initViews parses the views directory, only once for the app.
+func (a *App) initViews() {
+	a.viewsOnce.Do(func() {
+		if _, err := os.Stat(viewsDir); err != nil { // viewsDir not found: nothing to parse.
+			return
+		}
 
-	GlobalViewData(ViewData{
-		"envProduction": production,
-	})
+		a.GlobalViewData(ViewData{
+			"envProduction": production,
+		})
 
-	GlobalViewFuncs(ViewFuncs{
-		"asset": func(path string) string {
-			return staticHandler.Hash(path)
-		},
-		"script": func(src string) template.HTML {
-			return view.HelperScript(staticHandler.Hash("scripts/" + strings.TrimPrefix(src, "/")))
-		},
-		"style": func(href string) template.HTML {
-			return view.HelperStyle(staticHandler.Hash("styles/" + strings.TrimPrefix(href, "/")))
-		},
-	})
+		a.GlobalViewFuncs(ViewFuncs{
+			"asset": func(path string) string {
+				return staticHandler.Hash(path)
+			},
+			"script": func(src string) template.HTML {
+				return view.HelperScript(staticHandler.Hash("scripts/" + strings.TrimPrefix(src, "/")))
+			},
+			"style": func(href string) template.HTML {
+				return view.HelperStyle(staticHandler.Hash("styles/" + strings.TrimPrefix(href, "/")))
+			},
+			"url":      a.url,
+			"csrf":     csrfField,
+			"csrfMeta": csrfMeta,
+		})
 
-	views.ParseDir(viewsDir)
+		a.views.ParseDir(viewsDir)
+	})
 }
 
 func mergeViewData(dd []ViewData) view.Data {